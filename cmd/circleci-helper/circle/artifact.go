@@ -0,0 +1,94 @@
+package circle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Artifact describes a single artifact stored by a job.
+type Artifact struct {
+	Path      string `json:"path"`
+	NodeIndex int    `json:"node_index"`
+	URL       string `json:"url"`
+}
+
+// helper to deserialize response from CircleCI API
+type circleGetJobArtifactsResponse struct {
+	Items         []*Artifact `json:"items"`
+	NextPageToken string      `json:"next_page_token"`
+}
+
+// GetJobArtifacts retrieves artifacts stored by a specific job in a specific project.
+func (c *tokenBasedClient) GetJobArtifacts(ctx context.Context, projectType string, org string, project string, jobNumber int) ([]*Artifact, error) {
+	var result []*Artifact
+
+	pageToken := ""
+	for {
+		requestURL := fmt.Sprintf(
+			"https://circleci.com/api/v2/project/%s/%s/%s/%d/artifacts",
+			url.PathEscape(projectType), url.PathEscape(org), url.PathEscape(project),
+			jobNumber,
+		)
+		if pageToken != "" {
+			requestURL = fmt.Sprintf("%s?page-token=%s", requestURL, url.QueryEscape(pageToken))
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+		if err != nil {
+			return result, err
+		}
+
+		req.SetBasicAuth(c.token, "")
+		res, err := c.do(req)
+		if err != nil {
+			return result, err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode >= 400 {
+			return result, newClientHTTPErrorFromResponse(c.logger, res)
+		}
+
+		var response circleGetJobArtifactsResponse
+		if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+			return result, err
+		}
+
+		// combine results back into result as the API can use pagination
+		result = append(result, response.Items...)
+
+		if response.NextPageToken == "" {
+			break
+		}
+
+		pageToken = response.NextPageToken
+	}
+	return result, nil
+}
+
+// DownloadArtifact starts downloading contents of an artifact, returning a reader for its contents along with
+// its size, or -1 if the size is not known ; the reader has to be closed by the caller.
+func (c *tokenBasedClient) DownloadArtifact(ctx context.Context, artifact *Artifact) (io.ReadCloser, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", artifact.URL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// artifacts of private projects are only accessible with the token passed as a header
+	req.Header.Set("Circle-Token", c.token)
+	res, err := c.do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if res.StatusCode >= 400 {
+		defer res.Body.Close()
+		return nil, 0, newClientHTTPErrorFromResponse(c.logger, res)
+	}
+
+	return res.Body, res.ContentLength, nil
+}
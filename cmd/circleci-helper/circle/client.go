@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 
 	"go.uber.org/zap"
@@ -36,14 +37,34 @@ func (e *ClientHTTPError) Error() string {
 type Client interface {
 	// GetPipelineID returns UUID of the pipeline based on project type, org, name and pipeline number.
 	GetPipelineID(ctx context.Context, projectType string, org string, project string, pipelineNumber int) (string, error)
+	// GetPipeline returns the pipeline, including its VCS information, based on project type, org, name and pipeline number.
+	GetPipeline(ctx context.Context, projectType string, org string, project string, pipelineNumber int) (*Pipeline, error)
+	// ListPipelines retrieves pipelines of a project, most recent first, optionally limited to a branch.
+	ListPipelines(ctx context.Context, projectType string, org string, project string, opts ListPipelinesOptions) ([]*Pipeline, error)
+	// TriggerPipeline triggers a new pipeline for a project, for a branch or tag and with parameters.
+	TriggerPipeline(ctx context.Context, projectType string, org string, project string, opts TriggerPipelineOptions) (*Pipeline, error)
 	// GetWorkflows retrieves workflows for a specific pipeline ID.
 	GetWorkflows(ctx context.Context, pipelineID string) ([]*Workflow, error)
 	// GetWorkflowJobs retrieves jobs for a specific workflow ID.
 	GetWorkflowJobs(ctx context.Context, workflowID string) ([]*Job, error)
+	// CancelWorkflow cancels a specific workflow.
+	CancelWorkflow(ctx context.Context, workflowID string) error
+	// RerunWorkflow reruns a specific workflow, returning ID of the newly created workflow.
+	RerunWorkflow(ctx context.Context, workflowID string, opts RerunWorkflowOptions) (string, error)
+	// ApproveJob approves a pending approval job in a specific workflow.
+	ApproveJob(ctx context.Context, workflowID string, approvalRequestID string) error
 	// GetJobDetails retrieves details for a specific job in a specific project.
 	GetJobDetails(ctx context.Context, projectType string, org string, project string, jobNumber int) (*JobDetails, error)
 	// GetJobActionOutput retrieves output for a specific action.
 	GetJobActionOutput(ctx context.Context, action *JobAction) ([]JobOutputMessage, error)
+	// StreamJobActionOutput retrieves output for a specific action, passing messages to fn one at a time.
+	StreamJobActionOutput(ctx context.Context, action *JobAction, fn func(message JobOutputMessage) error) error
+	// GetJobArtifacts retrieves artifacts stored by a specific job in a specific project.
+	GetJobArtifacts(ctx context.Context, projectType string, org string, project string, jobNumber int) ([]*Artifact, error)
+	// GetJobTests retrieves test results stored by a specific job in a specific project.
+	GetJobTests(ctx context.Context, projectType string, org string, project string, jobNumber int) ([]*TestResult, error)
+	// DownloadArtifact starts downloading contents of an artifact, returning a reader for its contents and its size, or -1 if not known.
+	DownloadArtifact(ctx context.Context, artifact *Artifact) (io.ReadCloser, int64, error)
 }
 
 type tokenBasedClient struct {
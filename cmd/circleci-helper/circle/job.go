@@ -3,10 +3,15 @@ package circle
 // Job describes a single CircleCI job with fields that the tool is using.
 // These can be extended to map  more fields from responses as needed.
 type Job struct {
-	ID        string `json:"id"`
-	JobNumber int    `json:"job_number"`
-	Name      string `json:"name"`
-	Status    string `json:"status"`
+	ID                string   `json:"id"`
+	JobNumber         int      `json:"job_number"`
+	Name              string   `json:"name"`
+	Status            string   `json:"status"`
+	Type              string   `json:"type"`
+	ApprovalRequestID string   `json:"approval_request_id,omitempty"`
+	Dependencies      []string `json:"dependencies"`
+	StartedAt         string   `json:"started_at,omitempty"`
+	StoppedAt         string   `json:"stopped_at,omitempty"`
 }
 
 // JobFinished returns whether specified job has finished and is no longer in progress.
@@ -18,3 +23,13 @@ func JobFinished(job *Job) bool {
 func JobFailed(job *Job) bool {
 	return job.Status == "failed"
 }
+
+// JobIsApproval returns whether specified job is an approval job.
+func JobIsApproval(job *Job) bool {
+	return job.Type == "approval"
+}
+
+// JobAwaitingApproval returns whether specified job is an approval job that has not been approved yet.
+func JobAwaitingApproval(job *Job) bool {
+	return JobIsApproval(job) && job.Status == "on_hold"
+}
@@ -3,6 +3,7 @@ package circle
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -19,12 +20,22 @@ type JobStep struct {
 	Actions []JobAction `json:"actions"`
 }
 
-// JobAction describes a single action in a JobStep.
+// JobAction describes a single action in a JobStep ; steps of jobs with parallelism have one action per node.
 type JobAction struct {
 	Name      string `json:"name"`
 	Failed    bool   `json:"failed"`
 	OutputURL string `json:"output_url"`
 	HasOutput bool   `json:"has_output"`
+	// Index is the index of the node (container) that the action ran on.
+	Index int `json:"index"`
+	// Parallel is set if the job ran on multiple nodes.
+	Parallel bool   `json:"parallel"`
+	Status   string `json:"status"`
+	// ExitCode is the exit code of the command, if it has finished.
+	ExitCode      *int   `json:"exit_code"`
+	StartTime     string `json:"start_time,omitempty"`
+	EndTime       string `json:"end_time,omitempty"`
+	RunTimeMillis int64  `json:"run_time_millis"`
 }
 
 type JobOutputMessage struct {
@@ -48,7 +59,7 @@ func (c *tokenBasedClient) GetJobDetails(ctx context.Context, projectType string
 
 	req.SetBasicAuth(c.token, "")
 	req.Header.Add("Accept", "application/json")
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -66,28 +77,82 @@ func (c *tokenBasedClient) GetJobDetails(ctx context.Context, projectType string
 	return &response, nil
 }
 
-// GetJobActionOutput retrieves output for a specific action.
+// ErrStopOutput can be returned by the callback passed to StreamJobActionOutput to stop retrieving output without an error.
+var ErrStopOutput = errors.New("stop retrieving output")
+
+// JobActionHasOutput returns whether output can be retrieved for specified action.
+func JobActionHasOutput(action *JobAction) bool {
+	return action.HasOutput && action.OutputURL != ""
+}
+
+// GetJobActionOutput retrieves output for a specific action ; actions without output return no messages.
 func (c *tokenBasedClient) GetJobActionOutput(ctx context.Context, action *JobAction) ([]JobOutputMessage, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", action.OutputURL, nil)
+	response := []JobOutputMessage{}
+	err := c.StreamJobActionOutput(ctx, action, func(message JobOutputMessage) error {
+		response = append(response, message)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return response, nil
+}
+
+// StreamJobActionOutput retrieves output for a specific action, decoding messages one at a time and passing them
+// to fn so that whole output does not have to be kept in memory ; actions without output do not call fn.
+// If fn returns ErrStopOutput, retrieving output stops without an error.
+func (c *tokenBasedClient) StreamJobActionOutput(ctx context.Context, action *JobAction, fn func(message JobOutputMessage) error) error {
+	if !JobActionHasOutput(action) {
+		return nil
+	}
 
-	res, err := http.DefaultClient.Do(req)
+	req, err := http.NewRequestWithContext(ctx, "GET", action.OutputURL, nil)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	res, err := c.do(req)
+	if err != nil {
+		return err
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode >= 400 {
-		return nil, newClientHTTPErrorFromResponse(c.logger, res)
+		return newClientHTTPErrorFromResponse(c.logger, res)
 	}
 
-	var response []JobOutputMessage
+	dec := json.NewDecoder(res.Body)
 
-	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
-		return nil, err
+	// output is a JSON array of messages, decode its elements one at a time
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		// output of actions that did not produce any output may be null
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("unexpected output of action %s, expected an array", action.Name)
 	}
 
-	return response, nil
+	for dec.More() {
+		var message JobOutputMessage
+		if err := dec.Decode(&message); err != nil {
+			return err
+		}
+		if err := fn(message); err != nil {
+			if errors.Is(err, ErrStopOutput) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	// read the closing bracket to detect truncated responses
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+
+	return nil
 }
@@ -1,6 +1,7 @@
 package circle
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -8,9 +9,53 @@ import (
 	"net/url"
 )
 
+// Pipeline describes a single CircleCI pipeline.
+// These can be extended to map  more fields from responses as needed.
+type Pipeline struct {
+	ID        string      `json:"id"`
+	Number    int         `json:"number"`
+	State     string      `json:"state"`
+	CreatedAt string      `json:"created_at"`
+	VCS       PipelineVCS `json:"vcs"`
+	// Errors lists errors of the pipeline, such as invalid configuration, if its state is errored.
+	Errors []PipelineError `json:"errors,omitempty"`
+}
+
+// PipelineError describes an error that occurred while creating or setting up a pipeline.
+type PipelineError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// PipelineVCS describes version control information for a pipeline.
+type PipelineVCS struct {
+	ProviderName        string `json:"provider_name"`
+	OriginRepositoryURL string `json:"origin_repository_url"`
+	TargetRepositoryURL string `json:"target_repository_url"`
+	Revision            string `json:"revision"`
+	Branch              string `json:"branch"`
+	Tag                 string `json:"tag"`
+}
+
+// TriggerPipelineOptions allows specifying what to trigger a pipeline for ; only one of Branch and Tag may be set.
+type TriggerPipelineOptions struct {
+	Branch     string                 `json:"branch,omitempty"`
+	Tag        string                 `json:"tag,omitempty"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// ListPipelinesOptions allows limiting pipelines returned by ListPipelines.
+type ListPipelinesOptions struct {
+	// Branch limits pipelines to ones for the branch, if set.
+	Branch string
+	// Limit stops retrieving pipelines once at least this many were retrieved, if set.
+	Limit int
+}
+
 // helper to deserialize response from CircleCI API
-type circleGetPipelineIDResponse struct {
-	ID string `json:"id"`
+type circleListPipelinesResponse struct {
+	Items         []*Pipeline `json:"items"`
+	NextPageToken string      `json:"next_page_token"`
 }
 
 // helper to deserialize response from CircleCI API
@@ -21,30 +66,131 @@ type circleGetWorkflowsResponse struct {
 
 // GetPipelineID returns UUID of the pipeline based on project type, org, name and pipeline number.
 func (c *tokenBasedClient) GetPipelineID(ctx context.Context, projectType string, org string, project string, pipelineNumber int) (string, error) {
+	pipeline, err := c.GetPipeline(ctx, projectType, org, project, pipelineNumber)
+	if err != nil {
+		return "", err
+	}
+
+	return pipeline.ID, nil
+}
+
+// GetPipeline returns the pipeline based on project type, org, name and pipeline number.
+func (c *tokenBasedClient) GetPipeline(ctx context.Context, projectType string, org string, project string, pipelineNumber int) (*Pipeline, error) {
 	requestURL := fmt.Sprintf("https://circleci.com/api/v2/project/%s/%s/%s/pipeline/%d", url.PathEscape(projectType), url.PathEscape(org), url.PathEscape(project), pipelineNumber)
 	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	req.SetBasicAuth(c.token, "")
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	defer res.Body.Close()
 
 	if res.StatusCode >= 400 {
-		return "", newClientHTTPErrorFromResponse(c.logger, res)
+		return nil, newClientHTTPErrorFromResponse(c.logger, res)
 	}
 
-	var response circleGetPipelineIDResponse
+	var response Pipeline
 	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
-		return "", err
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// ListPipelines retrieves pipelines of a project, most recent first.
+func (c *tokenBasedClient) ListPipelines(ctx context.Context, projectType string, org string, project string, opts ListPipelinesOptions) ([]*Pipeline, error) {
+	var result []*Pipeline
+
+	pageToken := ""
+	for {
+		query := url.Values{}
+		if opts.Branch != "" {
+			query.Set("branch", opts.Branch)
+		}
+		if pageToken != "" {
+			query.Set("page-token", pageToken)
+		}
+		requestURL := fmt.Sprintf("https://circleci.com/api/v2/project/%s/%s/%s/pipeline", url.PathEscape(projectType), url.PathEscape(org), url.PathEscape(project))
+		if len(query) > 0 {
+			requestURL = fmt.Sprintf("%s?%s", requestURL, query.Encode())
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+		if err != nil {
+			return result, err
+		}
+
+		req.SetBasicAuth(c.token, "")
+		res, err := c.do(req)
+		if err != nil {
+			return result, err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode >= 400 {
+			return result, newClientHTTPErrorFromResponse(c.logger, res)
+		}
+
+		var response circleListPipelinesResponse
+		if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+			return result, err
+		}
+
+		// combine results back into result as the API can use pagination
+		result = append(result, response.Items...)
+
+		if response.NextPageToken == "" || (opts.Limit > 0 && len(result) >= opts.Limit) {
+			break
+		}
+
+		pageToken = response.NextPageToken
+	}
+
+	// the last page may contain more pipelines than requested
+	if opts.Limit > 0 && len(result) > opts.Limit {
+		result = result[:opts.Limit]
+	}
+	return result, nil
+}
+
+// TriggerPipeline triggers a new pipeline for a project, returning the pipeline without VCS information.
+func (c *tokenBasedClient) TriggerPipeline(ctx context.Context, projectType string, org string, project string, opts TriggerPipelineOptions) (*Pipeline, error) {
+	requestURL := fmt.Sprintf("https://circleci.com/api/v2/project/%s/%s/%s/pipeline", url.PathEscape(projectType), url.PathEscape(org), url.PathEscape(project))
+
+	body, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", requestURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.SetBasicAuth(c.token, "")
+	req.Header.Add("Content-Type", "application/json")
+	res, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		return nil, newClientHTTPErrorFromResponse(c.logger, res)
+	}
+
+	var response Pipeline
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, err
 	}
 
-	return response.ID, nil
+	return &response, nil
 }
 
 // GetWorkflows retrieves workflows for a specific pipeline ID.
@@ -64,7 +210,7 @@ func (c *tokenBasedClient) GetWorkflows(ctx context.Context, pipelineID string)
 		}
 
 		req.SetBasicAuth(c.token, "")
-		res, err := http.DefaultClient.Do(req)
+		res, err := c.do(req)
 		if err != nil {
 			return result, err
 		}
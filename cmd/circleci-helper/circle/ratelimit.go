@@ -0,0 +1,84 @@
+package circle
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// maxRateLimitRetries is the number of times a request is retried after CircleCI responded that it was rate limited.
+	maxRateLimitRetries = 5
+	// defaultRateLimitDelay is how long to wait before the first retry if CircleCI did not specify it ; it doubles for each retry.
+	defaultRateLimitDelay = time.Second
+	// maxRateLimitDelay is the maximum time to wait before a retry.
+	maxRateLimitDelay = time.Minute
+)
+
+// do sends a request, retrying it after waiting as long as CircleCI asked for if the request was rate limited.
+// Requests with a body are only retried if the body can be sent again.
+func (c *tokenBasedClient) do(req *http.Request) (*http.Response, error) {
+	for retry := 0; ; retry++ {
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode != http.StatusTooManyRequests || retry >= maxRateLimitRetries || (req.Body != nil && req.GetBody == nil) {
+			return res, nil
+		}
+
+		delay := rateLimitDelay(res.Header.Get("Retry-After"), retry, time.Now())
+		// read the rest of the body so that the connection can be reused
+		_, _ = io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+
+		c.logger.Warn("CircleCI API request was rate limited, retrying",
+			zap.String("url", req.URL.String()), zap.Duration("delay", delay), zap.Int("retry", retry+1))
+
+		if err := sleepContext(req.Context(), delay); err != nil {
+			return nil, err
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+// rateLimitDelay returns how long to wait before retrying a rate limited request, based on the Retry-After header
+// that can specify either a number of seconds or a date ; if it is not set, the delay increases with each retry.
+func rateLimitDelay(retryAfter string, retry int, now time.Time) time.Duration {
+	delay := defaultRateLimitDelay << retry
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(retryAfter); err == nil {
+		delay = date.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+	}
+	if delay > maxRateLimitDelay {
+		delay = maxRateLimitDelay
+	}
+	return delay
+}
+
+// sleepContext waits for the specified duration, returning an error if the context is done first.
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
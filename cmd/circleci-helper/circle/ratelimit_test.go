@@ -0,0 +1,127 @@
+package circle
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func Test_rateLimitDelay(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, test := range []struct {
+		name       string
+		retryAfter string
+		retry      int
+		expected   time.Duration
+	}{
+		{name: "seconds", retryAfter: "7", expected: 7 * time.Second},
+		{name: "zero seconds", retryAfter: "0", retry: 3, expected: 0},
+		{name: "seconds over the limit", retryAfter: "3600", expected: maxRateLimitDelay},
+		{name: "date", retryAfter: now.Add(30 * time.Second).Format(http.TimeFormat), expected: 30 * time.Second},
+		{name: "date in the past", retryAfter: now.Add(-time.Minute).Format(http.TimeFormat), expected: 0},
+		{name: "date over the limit", retryAfter: now.Add(time.Hour).Format(http.TimeFormat), expected: maxRateLimitDelay},
+		{name: "not set", expected: defaultRateLimitDelay},
+		{name: "not set, retried", retry: 3, expected: 8 * defaultRateLimitDelay},
+		{name: "not set, retried many times", retry: 10, expected: maxRateLimitDelay},
+		{name: "negative seconds", retryAfter: "-5", retry: 1, expected: 2 * defaultRateLimitDelay},
+		{name: "invalid", retryAfter: "soon", retry: 2, expected: 4 * defaultRateLimitDelay},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if want, got := test.expected, rateLimitDelay(test.retryAfter, test.retry, now); want != got {
+				t.Errorf("invalid delay; want %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func Test_tokenBasedClient_do(t *testing.T) {
+	var requests int
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if r.URL.Path == "/limited" || requests < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := &tokenBasedClient{logger: zap.NewNop()}
+
+	// rate limited requests are retried, sending the body again
+	req, err := http.NewRequest("POST", server.URL+"/ok", bytes.NewReader([]byte(`{"branch":"main"}`)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res, err := c.do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+	if want, got := http.StatusOK, res.StatusCode; want != got {
+		t.Errorf("invalid status code; want %v, got %v", want, got)
+	}
+	if want, got := 3, requests; want != got {
+		t.Errorf("invalid number of requests; want %v, got %v", want, got)
+	}
+	for i, body := range bodies {
+		if want, got := `{"branch":"main"}`, body; want != got {
+			t.Errorf("invalid body of request %d; want %q, got %q", i, want, got)
+		}
+	}
+
+	// requests are retried at most maxRateLimitRetries times
+	requests = 0
+	req, err = http.NewRequest("GET", server.URL+"/limited", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res, err = c.do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+	if want, got := http.StatusTooManyRequests, res.StatusCode; want != got {
+		t.Errorf("invalid status code; want %v, got %v", want, got)
+	}
+	if want, got := maxRateLimitRetries+1, requests; want != got {
+		t.Errorf("invalid number of requests; want %v, got %v", want, got)
+	}
+
+	// requests whose body cannot be sent again are not retried
+	requests = 0
+	req, err = http.NewRequest("POST", server.URL+"/limited", io.NopCloser(bytes.NewReader([]byte("{}"))))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res, err = c.do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+	if want, got := 1, requests; want != got {
+		t.Errorf("invalid number of requests; want %v, got %v", want, got)
+	}
+}
+
+func Test_sleepContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sleepContext(ctx, time.Hour); err != context.Canceled {
+		t.Errorf("invalid error; want %v, got %v", context.Canceled, err)
+	}
+	if err := sleepContext(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
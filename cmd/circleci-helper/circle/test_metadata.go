@@ -0,0 +1,79 @@
+package circle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// TestResult describes result of a single test, as parsed by CircleCI from test results stored by a job.
+type TestResult struct {
+	Name      string  `json:"name"`
+	Classname string  `json:"classname"`
+	File      string  `json:"file,omitempty"`
+	Result    string  `json:"result"`
+	Message   string  `json:"message,omitempty"`
+	Source    string  `json:"source,omitempty"`
+	RunTime   float64 `json:"run_time"`
+}
+
+// helper to deserialize response from CircleCI API
+type circleGetJobTestsResponse struct {
+	Items         []*TestResult `json:"items"`
+	NextPageToken string        `json:"next_page_token"`
+}
+
+// TestFailed returns whether specified test has failed.
+func TestFailed(test *TestResult) bool {
+	return test.Result == "failure" || test.Result == "error"
+}
+
+// GetJobTests retrieves test results stored by a specific job in a specific project.
+func (c *tokenBasedClient) GetJobTests(ctx context.Context, projectType string, org string, project string, jobNumber int) ([]*TestResult, error) {
+	var result []*TestResult
+
+	pageToken := ""
+	for {
+		requestURL := fmt.Sprintf(
+			"https://circleci.com/api/v2/project/%s/%s/%s/%d/tests",
+			url.PathEscape(projectType), url.PathEscape(org), url.PathEscape(project),
+			jobNumber,
+		)
+		if pageToken != "" {
+			requestURL = fmt.Sprintf("%s?page-token=%s", requestURL, url.QueryEscape(pageToken))
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+		if err != nil {
+			return result, err
+		}
+
+		req.SetBasicAuth(c.token, "")
+		res, err := c.do(req)
+		if err != nil {
+			return result, err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode >= 400 {
+			return result, newClientHTTPErrorFromResponse(c.logger, res)
+		}
+
+		var response circleGetJobTestsResponse
+		if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+			return result, err
+		}
+
+		// combine results back into result as the API can use pagination
+		result = append(result, response.Items...)
+
+		if response.NextPageToken == "" {
+			break
+		}
+
+		pageToken = response.NextPageToken
+	}
+	return result, nil
+}
@@ -1,6 +1,7 @@
 package circle
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -15,6 +16,7 @@ type Workflow struct {
 	Name      string `json:"name"`
 	Status    string `json:"status"`
 	CreatedAt string `json:"created_at"`
+	StoppedAt string `json:"stopped_at,omitempty"`
 }
 
 // helper to deserialize response from CircleCI API
@@ -40,7 +42,7 @@ func (c *tokenBasedClient) GetWorkflowJobs(ctx context.Context, workflowID strin
 		}
 
 		req.SetBasicAuth(c.token, "")
-		res, err := http.DefaultClient.Do(req)
+		res, err := c.do(req)
 		if err != nil {
 			return result, err
 		}
@@ -81,3 +83,100 @@ func WorkflowFailed(workflow *Workflow) bool {
 		workflow.Status == "canceled" ||
 		workflow.Status == "unauthorized"
 }
+
+// CancelWorkflow cancels a specific workflow.
+func (c *tokenBasedClient) CancelWorkflow(ctx context.Context, workflowID string) error {
+	requestURL := fmt.Sprintf("https://circleci.com/api/v2/workflow/%s/cancel", url.PathEscape(workflowID))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", requestURL, nil)
+	if err != nil {
+		return err
+	}
+
+	req.SetBasicAuth(c.token, "")
+	res, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		return newClientHTTPErrorFromResponse(c.logger, res)
+	}
+
+	return nil
+}
+
+// RerunWorkflowOptions describes how a workflow should be rerun.
+type RerunWorkflowOptions struct {
+	// FromFailed reruns the workflow from failed jobs.
+	FromFailed bool `json:"from_failed,omitempty"`
+	// Jobs lists IDs of specific jobs to rerun.
+	Jobs []string `json:"jobs,omitempty"`
+	// SparseTree only reruns specified jobs and jobs that depend on them, requires Jobs.
+	SparseTree bool `json:"sparse_tree,omitempty"`
+	// EnableSSH enables SSH access to rerun jobs for the user that the token belongs to, requires Jobs.
+	EnableSSH bool `json:"enable_ssh,omitempty"`
+}
+
+// helper to deserialize response from CircleCI API
+type circleRerunWorkflowResponse struct {
+	WorkflowID string `json:"workflow_id"`
+}
+
+// RerunWorkflow reruns a specific workflow, returning ID of the newly created workflow.
+func (c *tokenBasedClient) RerunWorkflow(ctx context.Context, workflowID string, opts RerunWorkflowOptions) (string, error) {
+	requestURL := fmt.Sprintf("https://circleci.com/api/v2/workflow/%s/rerun", url.PathEscape(workflowID))
+
+	body, err := json.Marshal(&opts)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", requestURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	req.SetBasicAuth(c.token, "")
+	req.Header.Add("Content-Type", "application/json")
+	res, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		return "", newClientHTTPErrorFromResponse(c.logger, res)
+	}
+
+	var response circleRerunWorkflowResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return "", err
+	}
+
+	return response.WorkflowID, nil
+}
+
+// ApproveJob approves a pending approval job in a specific workflow.
+func (c *tokenBasedClient) ApproveJob(ctx context.Context, workflowID string, approvalRequestID string) error {
+	requestURL := fmt.Sprintf("https://circleci.com/api/v2/workflow/%s/approve/%s", url.PathEscape(workflowID), url.PathEscape(approvalRequestID))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", requestURL, nil)
+	if err != nil {
+		return err
+	}
+
+	req.SetBasicAuth(c.token, "")
+	res, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		return newClientHTTPErrorFromResponse(c.logger, res)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/internal"
+)
+
+var artifactsPaths string
+var artifactsExcludePaths string
+var artifactsTargetDir string
+var artifactsMaxSize int64
+var artifactsDryRun bool
+var artifactsTimeout time.Duration
+
+// artifactsCmd represents the artifacts command
+var artifactsCmd = &cobra.Command{
+	Use:   "artifacts",
+	Short: "Download artifacts of jobs in a pipeline",
+	Long: `Downloads artifacts of finished jobs in a pipeline and prints paths of downloaded files. For example:
+
+circleci-helper artifacts --token ... --pipeline-number ... --org ... --project ... --include-jobs "build-*" --path "*.tar.gz"
+circleci-helper artifacts --token ... --pipeline-number ... --org ... --project ... --path "dist/*" --target-dir out
+
+Artifacts are written to <target-dir>/<job name>/<node index>/<artifact path>. Path patterns without a slash
+are also matched against the file name, so that "*.tar.gz" matches archives in any directory.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandHelper(cmd, args, artifactsMain)
+	},
+}
+
+func artifactsMain(logger *zap.Logger, cmd *cobra.Command, args []string) error {
+	if err := validateWorkflowFlags(); err != nil {
+		return err
+	}
+	if artifactsTargetDir == "" {
+		return fmt.Errorf("target-dir must be specified")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), artifactsTimeout)
+	defer cancel()
+
+	client := circle.NewClient(logger, circleAPIToken)
+
+	result, err := internal.DownloadArtifacts(ctx, logger, client, internal.DownloadArtifactsOptions{
+		ProjectType:      projectType,
+		Org:              org,
+		Project:          project,
+		PipelineNumber:   pipelineNumber,
+		WorkflowNames:    commaSeparatedListToSlice(workflow),
+		IncludeWorkflows: commaSeparatedListToSlice(includeWorkflows),
+		ExcludeWorkflows: commaSeparatedListToSlice(excludeWorkflows),
+		IncludeJobs:      commaSeparatedListToSlice(includeJobs),
+		ExcludeJobs:      commaSeparatedListToSlice(excludeJobs),
+		IncludePaths:     commaSeparatedListToSlice(artifactsPaths),
+		ExcludePaths:     commaSeparatedListToSlice(artifactsExcludePaths),
+		TargetDir:        artifactsTargetDir,
+		Concurrency:      concurrency,
+		MaxSize:          artifactsMaxSize,
+		DryRun:           artifactsDryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(result) == 0 {
+		logger.Sugar().Warnf("no artifacts matched criteria")
+	}
+
+	if !artifactsDryRun {
+		for _, artifact := range result {
+			fmt.Println(artifact.File)
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(artifactsCmd)
+
+	addWorkflowFlags(artifactsCmd)
+
+	artifactsCmd.Flags().StringVar(&includeJobs, "include-jobs", "", "job patterns to limit to (globs, or regular expressions prefixed with re:), comma separated list")
+	artifactsCmd.Flags().StringVar(&excludeJobs, "exclude-jobs", "", "job patterns to exclude (globs, or regular expressions prefixed with re:), comma separated list")
+	artifactsCmd.Flags().StringVar(&artifactsPaths, "path", "", "artifact path patterns to download (globs, or regular expressions prefixed with re:), comma separated list")
+	artifactsCmd.Flags().StringVar(&artifactsExcludePaths, "exclude-path", "", "artifact path patterns to skip (globs, or regular expressions prefixed with re:), comma separated list")
+	artifactsCmd.Flags().StringVar(&artifactsTargetDir, "target-dir", "artifacts", "directory to write artifacts to")
+	addConcurrencyFlag(artifactsCmd)
+	artifactsCmd.Flags().Int64Var(&artifactsMaxSize, "max-size", 0, "maximum size of a single artifact in bytes, 0 for no limit")
+	artifactsCmd.Flags().BoolVar(&artifactsDryRun, "dry-run", false, "only list artifacts that would be downloaded")
+	artifactsCmd.Flags().DurationVar(&artifactsTimeout, "timeout", 10*time.Minute, "time out for downloading artifacts")
+}
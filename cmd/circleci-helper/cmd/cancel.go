@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/internal"
+)
+
+var cancelOlderThanCurrent bool
+var cancelBranch string
+var cancelMaxPipelines int
+var cancelYes bool
+var cancelTimeout time.Duration
+
+// cancelCmd represents the cancel command
+var cancelCmd = &cobra.Command{
+	Use:   "cancel",
+	Short: "Cancel running workflows of a pipeline or of older pipelines on the same branch",
+	Long: `Cancels workflows that have not finished yet. For example:
+
+circleci-helper cancel --token ... --pipeline-number ... --org ... --project ... --workflow "myworkflow" --yes
+circleci-helper cancel --token ... --pipeline-number ... --org ... --project ... --older-than-current --branch main --yes
+
+With --older-than-current, workflows of the pipeline itself are left running and workflows of older pipelines
+on the same branch are canceled instead. The branch defaults to the branch of the pipeline.
+
+Only lists workflows that would be canceled unless --yes is specified.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandHelper(cmd, args, cancelMain)
+	},
+}
+
+func cancelMain(logger *zap.Logger, cmd *cobra.Command, args []string) error {
+	sugar := logger.Sugar()
+
+	if err := validateWorkflowFlags(); err != nil {
+		return err
+	}
+	if cancelBranch != "" && !cancelOlderThanCurrent {
+		return fmt.Errorf("--branch can only be used with --older-than-current")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+	defer cancel()
+
+	client := circle.NewClient(logger, circleAPIToken)
+
+	dryRun := !cancelYes
+	if dryRun {
+		sugar.Infof("dry run, no workflows will be canceled; use --yes to cancel them")
+	}
+
+	var canceled []*circle.Workflow
+	var err error
+	if cancelOlderThanCurrent {
+		canceled, err = internal.CancelOlderPipelines(ctx, logger, client, internal.CancelOlderPipelinesOptions{
+			ProjectType:      projectType,
+			Org:              org,
+			Project:          project,
+			PipelineNumber:   pipelineNumber,
+			Branch:           cancelBranch,
+			MaxPipelines:     cancelMaxPipelines,
+			WorkflowNames:    commaSeparatedListToSlice(workflow),
+			IncludeWorkflows: commaSeparatedListToSlice(includeWorkflows),
+			ExcludeWorkflows: commaSeparatedListToSlice(excludeWorkflows),
+			DryRun:           dryRun,
+		})
+	} else {
+		canceled, err = internal.CancelWorkflows(ctx, logger, client, internal.CancelWorkflowsOptions{
+			ProjectType:      projectType,
+			Org:              org,
+			Project:          project,
+			PipelineNumber:   pipelineNumber,
+			WorkflowNames:    commaSeparatedListToSlice(workflow),
+			IncludeWorkflows: commaSeparatedListToSlice(includeWorkflows),
+			ExcludeWorkflows: commaSeparatedListToSlice(excludeWorkflows),
+			DryRun:           dryRun,
+		})
+	}
+
+	// report workflows that were canceled even if canceling some of them failed
+	if dryRun {
+		sugar.Infof("%d workflows would be canceled", len(canceled))
+	} else {
+		sugar.Infof("%d workflows canceled", len(canceled))
+	}
+
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(cancelCmd)
+
+	addWorkflowFlags(cancelCmd)
+
+	cancelCmd.Flags().BoolVar(&cancelOlderThanCurrent, "older-than-current", false, "cancel workflows of older pipelines on the same branch instead of the pipeline itself")
+	cancelCmd.Flags().StringVar(&cancelBranch, "branch", "", "branch to cancel older pipelines on with --older-than-current, branch of the pipeline if not specified")
+	cancelCmd.Flags().IntVar(&cancelMaxPipelines, "max-pipelines", 100, "maximum number of most recent pipelines on the branch to check with --older-than-current")
+	cancelCmd.Flags().BoolVar(&cancelYes, "yes", false, "cancel workflows instead of only listing them")
+	cancelCmd.Flags().DurationVar(&cancelTimeout, "timeout", time.Minute, "time out for canceling workflows")
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/internal"
+)
+
+// common flags and variables for commands that can report results to GitHub
+var githubReport string
+var githubContext string
+var githubRepo string
+
+// addGitHubFlags adds flags for reporting results to GitHub to the given command.
+func addGitHubFlags(command *cobra.Command) {
+	command.Flags().StringVar(&githubReport, "github-report", "", "report outcome to GitHub as a commit status (status) or a check run (check)")
+	command.Flags().StringVar(&githubContext, "github-context", "circleci-helper", "name of the GitHub commit status or check run")
+	command.Flags().StringVar(&githubRepo, "github-repo", "", "GitHub repository as owner/name (default is taken from pipeline VCS information)")
+	command.Flags().String("github-api-url", internal.DefaultGitHubAPIURL, "GitHub API base URL (i.e. https://github.example.com/api/v3 for GitHub Enterprise Server)")
+	command.Flags().String("github-token", "", "GitHub API token (can also be set as github-token in config file or GITHUB_TOKEN environment variable)")
+
+	// allow specifying GitHub settings in config file and token as environment variable
+	cobra.CheckErr(viper.BindPFlag("github-api-url", command.Flags().Lookup("github-api-url")))
+	cobra.CheckErr(viper.BindPFlag("github-token", command.Flags().Lookup("github-token")))
+	cobra.CheckErr(viper.BindEnv("github-token", "GITHUB_TOKEN"))
+}
+
+// newGitHubReporter creates a reporter based on GitHub flags, returning nil if reporting to GitHub was not requested.
+func newGitHubReporter(ctx context.Context, logger *zap.Logger, client circle.Client) (*internal.GitHubReporter, error) {
+	if githubReport == "" {
+		return nil, nil
+	}
+
+	pipeline, err := client.GetPipeline(ctx, projectType, org, project, pipelineNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, repo, ok := internal.GitHubRepositoryFromURL("https://github.com/" + githubRepo)
+	if githubRepo == "" {
+		owner, repo, ok = internal.GitHubRepositoryFromURL(pipeline.VCS.TargetRepositoryURL)
+		if !ok {
+			owner, repo, ok = org, project, true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("invalid GitHub repository %q, must be owner/name", githubRepo)
+	}
+
+	return internal.NewGitHubReporter(logger, internal.GitHubOptions{
+		APIURL: viper.GetString("github-api-url"),
+		Token:  viper.GetString("github-token"),
+		Owner:  owner,
+		Repo:   repo,
+		Mode:   githubReport,
+		Name:   githubContext,
+		TargetURL: fmt.Sprintf(
+			"https://app.circleci.com/pipelines/%s/%s/%s/%d",
+			url.PathEscape(projectType), url.PathEscape(org), url.PathEscape(project),
+			pipelineNumber,
+		),
+	}, pipeline.VCS.Revision)
+}
@@ -9,14 +9,38 @@ import (
 	"go.uber.org/zap"
 )
 
-// convert comma separated list into an array, trimming spaces and ignoring empty values
+// exit codes used by commands
+const (
+	// exitCodeError is used when a command could not be run, such as when CircleCI API returned an error
+	exitCodeError = 1
+	// exitCodeFailed is used when one or more workflows or jobs have failed
+	exitCodeFailed = 2
+)
+
+// convert comma separated list into an array, trimming spaces and ignoring empty values ; commas inside braces or
+// brackets do not separate values, so that patterns such as re:test-\d{1,3} are kept as a single value
 func commaSeparatedListToSlice(value string) (result []string) {
-	for _, val := range strings.Split(value, ",") {
+	add := func(val string) {
 		val = strings.TrimSpace(val)
 		if val != "" {
 			result = append(result, val)
 		}
 	}
+
+	depth := 0
+	start := 0
+	for i, c := range value {
+		switch {
+		case c == '{' || c == '[':
+			depth++
+		case (c == '}' || c == ']') && depth > 0:
+			depth--
+		case c == ',' && depth == 0:
+			add(value[start:i])
+			start = i + 1
+		}
+	}
+	add(value[start:])
 	return result
 }
 
@@ -27,7 +51,7 @@ func commandHelper(cmd *cobra.Command, args []string, mainFunction func(logger *
 	logger, err := config.Build()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing command: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCodeError)
 	}
 
 	defer logger.Sync()
@@ -35,6 +59,6 @@ func commandHelper(cmd *cobra.Command, args []string, mainFunction func(logger *
 	err = mainFunction(logger, cmd, args)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running command: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCodeError)
 	}
 }
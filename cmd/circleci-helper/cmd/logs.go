@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/internal"
+)
+
+var logsJobNumber int
+var logsJobNames string
+var logsSteps string
+var logsNode int
+var logsFailedOnly bool
+var logsTail int
+var logsGrep string
+var logsSearch string
+var logsContextLines int
+var logsTimeout time.Duration
+
+// logsCmd represents the logs command
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Print or search output of jobs",
+	Long: `Prints output of steps of a job, selected either by its number or by its name in a pipeline. For example:
+
+circleci-helper logs --token ... --org ... --project ... --job-number 1234 --failed-only --tail 100
+circleci-helper logs --token ... --pipeline-number ... --org ... --project ... --job test --step "Run tests" --grep "FAIL"
+circleci-helper logs --token ... --pipeline-number ... --org ... --project ... --search "connection refused"
+
+With --grep, only matching lines are printed along with --context lines around them, prefixed with their line numbers.
+With --search, output of all jobs in the pipeline (limited by workflow and job flags) is searched concurrently.
+Output of each step is preceded by a line starting with "==>" describing the job, step and node.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandHelper(cmd, args, logsMain)
+	},
+}
+
+func logsMain(logger *zap.Logger, cmd *cobra.Command, args []string) error {
+	if err := validateProjectFlags(); err != nil {
+		return err
+	}
+
+	grep := logsGrep
+	switch {
+	case logsSearch != "":
+		if logsJobNumber != 0 || logsJobNames != "" {
+			return fmt.Errorf("search cannot be combined with job-number or job")
+		}
+		if logsGrep != "" {
+			return fmt.Errorf("search cannot be combined with grep")
+		}
+		grep = logsSearch
+	case logsJobNumber != 0:
+		if logsJobNames != "" {
+			return fmt.Errorf("job-number cannot be combined with job")
+		}
+	case logsJobNames != "":
+	default:
+		return fmt.Errorf("one of job-number, job or search must be specified")
+	}
+	if logsJobNumber == 0 && pipelineNumber == 0 {
+		return fmt.Errorf("pipeline-number must be specified")
+	}
+
+	var nodes []int
+	if logsNode >= 0 {
+		nodes = []int{logsNode}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), logsTimeout)
+	defer cancel()
+
+	client := circle.NewClient(logger, circleAPIToken)
+
+	summary, err := internal.WriteJobLogs(ctx, logger, client, internal.JobLogsOptions{
+		ProjectType:      projectType,
+		Org:              org,
+		Project:          project,
+		JobNumber:        logsJobNumber,
+		PipelineNumber:   pipelineNumber,
+		WorkflowNames:    commaSeparatedListToSlice(workflow),
+		IncludeWorkflows: commaSeparatedListToSlice(includeWorkflows),
+		ExcludeWorkflows: commaSeparatedListToSlice(excludeWorkflows),
+		JobNames:         commaSeparatedListToSlice(logsJobNames),
+		IncludeJobs:      commaSeparatedListToSlice(includeJobs),
+		ExcludeJobs:      commaSeparatedListToSlice(excludeJobs),
+		Steps:            commaSeparatedListToSlice(logsSteps),
+		Nodes:            nodes,
+		FailedOnly:       logsFailedOnly,
+		Tail:             logsTail,
+		Grep:             grep,
+		ContextLines:     logsContextLines,
+		Concurrency:      concurrency,
+	}, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	sugar := logger.Sugar()
+	if grep != "" {
+		sugar.Infof("found %d matching lines in %d steps of %d jobs", summary.Matches, summary.Steps, summary.Jobs)
+	} else if summary.Steps == 0 {
+		sugar.Warnf("no steps matched criteria")
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+
+	addWorkflowFlags(logsCmd)
+
+	logsCmd.Flags().IntVar(&logsJobNumber, "job-number", 0, "number of the job to print output of")
+	logsCmd.Flags().StringVar(&logsJobNames, "job", "", "names of jobs in the pipeline to print output of, comma separated list")
+	logsCmd.Flags().StringVar(&includeJobs, "include-jobs", "", "job patterns to limit to (globs, or regular expressions prefixed with re:), comma separated list")
+	logsCmd.Flags().StringVar(&excludeJobs, "exclude-jobs", "", "job patterns to exclude (globs, or regular expressions prefixed with re:), comma separated list")
+	logsCmd.Flags().StringVar(&logsSteps, "step", "", "step patterns to limit to (globs, or regular expressions prefixed with re:), comma separated list")
+	logsCmd.Flags().IntVar(&logsNode, "node", -1, "only print output of the node with this index, for jobs with parallelism")
+	logsCmd.Flags().BoolVar(&logsFailedOnly, "failed-only", false, "only print output of failed steps")
+	logsCmd.Flags().IntVar(&logsTail, "tail", 0, "only print the last lines of output of each step, 0 for whole output")
+	logsCmd.Flags().StringVar(&logsGrep, "grep", "", "regular expression ; only print matching lines of output")
+	logsCmd.Flags().StringVar(&logsSearch, "search", "", "regular expression to search for in output of all jobs in the pipeline")
+	logsCmd.Flags().IntVar(&logsContextLines, "context", 2, "with --grep or --search, number of lines to print before and after matching lines")
+	addConcurrencyFlag(logsCmd)
+	logsCmd.Flags().DurationVar(&logsTimeout, "timeout", 5*time.Minute, "time out for retrieving output")
+}
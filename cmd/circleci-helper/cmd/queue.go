@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/internal"
+)
+
+var queueBranch string
+var queueJob string
+var queueMaxPipelines int
+var queueMaxWait time.Duration
+var queueDontQuit bool
+var queueTimeout time.Duration
+var queueWaitTime time.Duration
+
+// queueCmd represents the queue command
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Wait for older pipelines on the same branch to finish",
+	Long: `Blocks until older pipelines on the same branch have finished, so that only one pipeline at a time
+proceeds past this point, such as when deploying. For example:
+
+circleci-helper queue --token ... --pipeline-number ... --org ... --project ... --workflow "deploy"
+circleci-helper queue --token ... --pipeline-number ... --org ... --project ... --job "deploy" --max-wait 30m --dont-quit
+
+The lock is scoped to the branch of the pipeline, or the one specified with --branch, and can be further scoped to
+workflows with --workflow, --include-workflows and --exclude-workflows. With --job, older pipelines only hold the lock
+until the job with this name has finished.
+
+Exits with an error if older pipelines are still running after --max-wait, unless --dont-quit is specified.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandHelper(cmd, args, queueMain)
+	},
+}
+
+func queueMain(logger *zap.Logger, cmd *cobra.Command, args []string) error {
+	if err := validateWorkflowFlags(); err != nil {
+		return err
+	}
+	if queueMaxWait > 0 && queueMaxWait >= queueTimeout {
+		return fmt.Errorf("--max-wait must be shorter than --timeout")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), queueTimeout)
+	defer cancel()
+
+	client := circle.NewClient(logger, circleAPIToken)
+
+	err := internal.WaitForQueue(ctx, logger, client, internal.WaitForQueueOptions{
+		ProjectType:      projectType,
+		Org:              org,
+		Project:          project,
+		PipelineNumber:   pipelineNumber,
+		Branch:           queueBranch,
+		WorkflowNames:    commaSeparatedListToSlice(workflow),
+		IncludeWorkflows: commaSeparatedListToSlice(includeWorkflows),
+		ExcludeWorkflows: commaSeparatedListToSlice(excludeWorkflows),
+		JobName:          queueJob,
+		MaxPipelines:     queueMaxPipelines,
+		WaitDuration:     internal.NewWaitForJobsDuration(queueWaitTime),
+		MaxWait:          queueMaxWait,
+		DontQuit:         queueDontQuit,
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for older pipelines after %v", queueTimeout)
+	}
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(queueCmd)
+
+	addWorkflowFlags(queueCmd)
+
+	queueCmd.Flags().StringVar(&queueBranch, "branch", "", "branch to wait for older pipelines on, branch of the pipeline if not specified")
+	queueCmd.Flags().StringVar(&queueJob, "job", "", "name of the job that older pipelines hold the lock until, all matching workflows if not specified")
+	queueCmd.Flags().IntVar(&queueMaxPipelines, "max-pipelines", 100, "maximum number of most recent pipelines on the branch to check")
+	queueCmd.Flags().DurationVar(&queueMaxWait, "max-wait", 30*time.Minute, "maximum time to wait for older pipelines, 0 to wait until --timeout")
+	queueCmd.Flags().BoolVar(&queueDontQuit, "dont-quit", false, "proceed once --max-wait has passed instead of failing")
+	queueCmd.Flags().DurationVar(&queueTimeout, "timeout", time.Hour, "time out for waiting for older pipelines")
+	queueCmd.Flags().DurationVar(&queueWaitTime, "wait-time", 10*time.Second, "time to wait between performing checks")
+}
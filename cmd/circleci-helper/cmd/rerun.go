@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/internal"
+)
+
+var rerunFromFailed bool
+var rerunJobs string
+var rerunSparseTree bool
+var rerunEnableSSH bool
+var rerunTimeout time.Duration
+
+// rerunCmd represents the rerun command
+var rerunCmd = &cobra.Command{
+	Use:   "rerun",
+	Short: "Rerun workflows of a pipeline",
+	Long: `Reruns finished workflows of a pipeline and prints URLs of the new workflows. For example:
+
+circleci-helper rerun --token ... --pipeline-number ... --org ... --project ... --workflow "myworkflow" --from-failed
+circleci-helper rerun --token ... --pipeline-number ... --org ... --project ... --jobs "test,lint" --enable-ssh
+
+--sparse-tree and --enable-ssh require --jobs, which cannot be combined with --from-failed.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandHelper(cmd, args, rerunMain)
+	},
+}
+
+func rerunMain(logger *zap.Logger, cmd *cobra.Command, args []string) error {
+	if err := validateWorkflowFlags(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rerunTimeout)
+	defer cancel()
+
+	client := circle.NewClient(logger, circleAPIToken)
+
+	result, err := internal.RerunWorkflows(ctx, logger, client, internal.RerunWorkflowsOptions{
+		ProjectType:      projectType,
+		Org:              org,
+		Project:          project,
+		PipelineNumber:   pipelineNumber,
+		WorkflowNames:    commaSeparatedListToSlice(workflow),
+		IncludeWorkflows: commaSeparatedListToSlice(includeWorkflows),
+		ExcludeWorkflows: commaSeparatedListToSlice(excludeWorkflows),
+		FromFailed:       rerunFromFailed,
+		Jobs:             commaSeparatedListToSlice(rerunJobs),
+		SparseTree:       rerunSparseTree,
+		EnableSSH:        rerunEnableSSH,
+	})
+
+	// report workflows that were rerun even if rerunning some of them failed
+	if len(result) > 0 {
+		fmt.Printf("Rerun workflows:\n")
+		for _, rerun := range result {
+			printWorkflowNameAndURL(&circle.Workflow{ID: rerun.NewWorkflowID, Name: rerun.Workflow.Name})
+		}
+	} else if err == nil {
+		logger.Sugar().Warnf("no workflows were rerun")
+	}
+
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(rerunCmd)
+
+	addWorkflowFlags(rerunCmd)
+
+	rerunCmd.Flags().BoolVar(&rerunFromFailed, "from-failed", false, "rerun workflows from failed jobs, only rerunning workflows that have failed")
+	rerunCmd.Flags().StringVar(&rerunJobs, "jobs", "", "names of jobs to rerun, comma separated list")
+	rerunCmd.Flags().BoolVar(&rerunSparseTree, "sparse-tree", false, "only rerun specified jobs and jobs that depend on them, requires --jobs")
+	rerunCmd.Flags().BoolVar(&rerunEnableSSH, "enable-ssh", false, "enable SSH access to rerun jobs, requires --jobs")
+	rerunCmd.Flags().DurationVar(&rerunTimeout, "timeout", time.Minute, "time out for rerunning workflows")
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/internal"
+)
+
+// output formats of the status command
+const (
+	statusFormatText     = "text"
+	statusFormatJSON     = "json"
+	statusFormatTemplate = "template"
+)
+
+var statusFormat string
+var statusTemplate string
+var statusTimeout time.Duration
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show current status of workflows and jobs of a pipeline",
+	Long: `Shows current status of workflows and jobs of a pipeline, without waiting for them to finish. For example:
+
+circleci-helper status --token ... --pipeline-number ... --org ... --project ...
+circleci-helper status ... --format json
+circleci-helper status ... --format template --template '{{range .Workflows}}{{.Name}}: {{.Status}}{{"\n"}}{{end}}'
+
+Exits with exit code 2 if one or more workflows or jobs have failed.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandHelper(cmd, args, statusMain)
+	},
+}
+
+// printStatusText prints status of workflows and their jobs as a table.
+func printStatusText(w io.Writer, status *internal.PipelineStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "WORKFLOW\tJOB\tSTATUS\tDURATION\tNUMBER\tURL")
+	for _, workflow := range status.Workflows {
+		fmt.Fprintf(tw, "%s\t-\t%s\t%s\t-\t%s\n", workflow.Name, workflow.Status, valueOrDash(workflow.Duration), workflow.URL)
+		for _, job := range workflow.Jobs {
+			number := "-"
+			if job.Number != 0 {
+				number = fmt.Sprintf("%d", job.Number)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", workflow.Name, job.Name, job.Status, valueOrDash(job.Duration), number, valueOrDash(job.URL))
+		}
+	}
+	return tw.Flush()
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func validateStatusFlags() error {
+	switch statusFormat {
+	case statusFormatText, statusFormatJSON:
+		if statusTemplate != "" {
+			return fmt.Errorf("--template can only be used with --format %s", statusFormatTemplate)
+		}
+	case statusFormatTemplate:
+		if statusTemplate == "" {
+			return fmt.Errorf("--template must be specified with --format %s", statusFormatTemplate)
+		}
+	default:
+		return fmt.Errorf("invalid format %q, must be %s, %s or %s", statusFormat, statusFormatText, statusFormatJSON, statusFormatTemplate)
+	}
+	return nil
+}
+
+func statusMain(logger *zap.Logger, cmd *cobra.Command, args []string) error {
+	if err := validateWorkflowFlags(); err != nil {
+		return err
+	}
+	if err := validateStatusFlags(); err != nil {
+		return err
+	}
+
+	// parse the template before making any requests to report errors early
+	var tmpl *template.Template
+	if statusFormat == statusFormatTemplate {
+		var err error
+		tmpl, err = template.New("status").Parse(statusTemplate)
+		if err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+	defer cancel()
+
+	client := circle.NewClient(logger, circleAPIToken)
+
+	status, err := internal.GetPipelineStatus(ctx, client, internal.StatusOptions{
+		ProjectType:      projectType,
+		Org:              org,
+		Project:          project,
+		PipelineNumber:   pipelineNumber,
+		WorkflowNames:    commaSeparatedListToSlice(workflow),
+		IncludeWorkflows: commaSeparatedListToSlice(includeWorkflows),
+		ExcludeWorkflows: commaSeparatedListToSlice(excludeWorkflows),
+		IncludeJobs:      commaSeparatedListToSlice(includeJobs),
+		ExcludeJobs:      commaSeparatedListToSlice(excludeJobs),
+		Where:            where,
+		Concurrency:      concurrency,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch statusFormat {
+	case statusFormatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(status)
+	case statusFormatTemplate:
+		err = tmpl.Execute(os.Stdout, status)
+	default:
+		err = printStatusText(os.Stdout, status)
+	}
+	if err != nil {
+		return err
+	}
+
+	if status.Failed {
+		os.Exit(exitCodeFailed)
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	addWorkflowFlags(statusCmd)
+
+	statusCmd.Flags().StringVar(&includeJobs, "include-jobs", "", "job patterns to limit to (globs, or regular expressions prefixed with re:), comma separated list")
+	statusCmd.Flags().StringVar(&excludeJobs, "exclude-jobs", "", "job patterns to exclude (globs, or regular expressions prefixed with re:), comma separated list")
+	statusCmd.Flags().StringVar(&where, "where", "", `expression selecting workflows and jobs, i.e. 'workflow.name like "deploy-*" and not job.approval'`)
+	statusCmd.Flags().StringVar(&statusFormat, "format", statusFormatText, "output format: text, json or template")
+	statusCmd.Flags().StringVar(&statusTemplate, "template", "", "Go template to format output with, used with --format template")
+	addConcurrencyFlag(statusCmd)
+	statusCmd.Flags().DurationVar(&statusTimeout, "timeout", time.Minute, "time out for retrieving status")
+}
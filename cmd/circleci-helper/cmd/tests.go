@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/internal"
+)
+
+// output formats of the tests command
+const (
+	testsFormatText = "text"
+	testsFormatJSON = "json"
+)
+
+var testsFormat string
+var testsTimeout time.Duration
+
+// testsCmd represents the tests command
+var testsCmd = &cobra.Command{
+	Use:   "tests",
+	Short: "Report failed tests across jobs of a pipeline",
+	Long: `Reports tests that failed in jobs of a pipeline, based on test results stored by the jobs. For example:
+
+circleci-helper tests --token ... --pipeline-number ... --org ... --project ...
+circleci-helper tests ... --include-jobs "test-*" --format json
+
+Failures of the same test in multiple jobs, such as parallel runs or matrix jobs, are reported together.
+Exits with exit code 2 if one or more tests have failed.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandHelper(cmd, args, testsMain)
+	},
+}
+
+// describeTest returns a human-friendly name of a test, including its file if known.
+func describeTest(classname string, name string, file string) string {
+	description := name
+	if classname != "" {
+		description = classname + " " + name
+	}
+	if file != "" {
+		description = fmt.Sprintf("%s (%s)", description, file)
+	}
+	return description
+}
+
+// indentLines prefixes each line of text with indent.
+func indentLines(text string, indent string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	return indent + strings.Join(lines, "\n"+indent)
+}
+
+// printTestFailuresText prints failed tests along with jobs they failed in.
+func printTestFailuresText(w io.Writer, result *internal.TestFailuresResult) {
+	if len(result.Failures) == 0 {
+		fmt.Fprintf(w, "No failed tests in %d failed jobs\n", result.FailedJobs)
+		return
+	}
+
+	fmt.Fprintf(w, "Failed tests (%d):\n", len(result.Failures))
+	for _, failure := range result.Failures {
+		fmt.Fprintf(w, "  %s\n", describeTest(failure.Classname, failure.Name, failure.File))
+		for _, occurrence := range failure.Occurrences {
+			fmt.Fprintf(w, "    failed in workflow %s job %s\n", occurrence.Workflow.Name, occurrence.Job.Name)
+			if occurrence.Message != "" {
+				fmt.Fprintf(w, "%s\n", indentLines(occurrence.Message, "      "))
+			}
+		}
+	}
+
+	if result.FailedJobsWithoutTests > 0 {
+		fmt.Fprintf(w, "%d failed jobs did not report any failed tests\n", result.FailedJobsWithoutTests)
+	}
+}
+
+func testsMain(logger *zap.Logger, cmd *cobra.Command, args []string) error {
+	if err := validateWorkflowFlags(); err != nil {
+		return err
+	}
+	if testsFormat != testsFormatText && testsFormat != testsFormatJSON {
+		return fmt.Errorf("invalid format %q, must be %s or %s", testsFormat, testsFormatText, testsFormatJSON)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testsTimeout)
+	defer cancel()
+
+	client := circle.NewClient(logger, circleAPIToken)
+
+	result, err := internal.GetTestFailures(ctx, logger, client, internal.TestFailuresOptions{
+		ProjectType:      projectType,
+		Org:              org,
+		Project:          project,
+		PipelineNumber:   pipelineNumber,
+		WorkflowNames:    commaSeparatedListToSlice(workflow),
+		IncludeWorkflows: commaSeparatedListToSlice(includeWorkflows),
+		ExcludeWorkflows: commaSeparatedListToSlice(excludeWorkflows),
+		IncludeJobs:      commaSeparatedListToSlice(includeJobs),
+		ExcludeJobs:      commaSeparatedListToSlice(excludeJobs),
+	})
+	if err != nil {
+		return err
+	}
+
+	if testsFormat == testsFormatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	} else {
+		printTestFailuresText(os.Stdout, result)
+	}
+
+	if len(result.Failures) > 0 {
+		os.Exit(exitCodeFailed)
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(testsCmd)
+
+	addWorkflowFlags(testsCmd)
+
+	testsCmd.Flags().StringVar(&includeJobs, "include-jobs", "", "job patterns to limit to (globs, or regular expressions prefixed with re:), comma separated list")
+	testsCmd.Flags().StringVar(&excludeJobs, "exclude-jobs", "", "job patterns to exclude (globs, or regular expressions prefixed with re:), comma separated list")
+	testsCmd.Flags().StringVar(&testsFormat, "format", testsFormatText, "output format: text or json")
+	testsCmd.Flags().DurationVar(&testsTimeout, "timeout", time.Minute, "time out for retrieving test results")
+}
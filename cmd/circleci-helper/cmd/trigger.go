@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/internal"
+)
+
+var triggerBranch string
+var triggerTag string
+var triggerParameters []string
+var triggerParametersFile string
+var triggerWait bool
+var triggerTimeout time.Duration
+var triggerWaitTime time.Duration
+
+// triggerCmd represents the trigger command
+var triggerCmd = &cobra.Command{
+	Use:   "trigger",
+	Short: "Trigger a pipeline, optionally waiting for it to finish",
+	Long: `Triggers a new pipeline for a project and prints its number. For example:
+
+circleci-helper trigger --token ... --org ... --project ... --branch main --parameter run-integration-tests=true
+circleci-helper trigger --token ... --org ... --project ... --tag v1.0.0 --parameters-file params.yml --wait
+
+Parameters specified with --parameter override ones loaded from --parameters-file. With --wait, waits for
+workflows of the new pipeline the same way as wait-for-jobs and exits with exit code 2 if any of them failed.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandHelper(cmd, args, triggerMain)
+	},
+}
+
+// triggerPipelineParameters returns parameters from --parameters-file and --parameter flags.
+func triggerPipelineParameters() (map[string]interface{}, error) {
+	parameters := map[string]interface{}{}
+	if triggerParametersFile != "" {
+		var err error
+		parameters, err = internal.LoadPipelineParameters(triggerParametersFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	overrides, err := internal.ParsePipelineParameters(triggerParameters)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range overrides {
+		parameters[name] = value
+	}
+
+	return parameters, nil
+}
+
+func triggerMain(logger *zap.Logger, cmd *cobra.Command, args []string) error {
+	sugar := logger.Sugar()
+
+	if err := validateProjectFlags(); err != nil {
+		return err
+	}
+	if triggerBranch != "" && triggerTag != "" {
+		return fmt.Errorf("only one of --branch and --tag can be specified")
+	}
+
+	parameters, err := triggerPipelineParameters()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), triggerTimeout)
+	defer cancel()
+
+	client := circle.NewClient(logger, circleAPIToken)
+
+	pipeline, err := internal.TriggerPipeline(ctx, logger, client, internal.TriggerPipelineOptions{
+		ProjectType: projectType,
+		Org:         org,
+		Project:     project,
+		Branch:      triggerBranch,
+		Tag:         triggerTag,
+		Parameters:  parameters,
+	})
+	if err != nil {
+		return err
+	}
+
+	// print the pipeline number so that it can be used in scripts, logs are written to standard error
+	fmt.Println(pipeline.Number)
+
+	if !triggerWait {
+		return nil
+	}
+
+	pipelineNumber = pipeline.Number
+	opts := waitForJobsOptions()
+	opts.WaitDuration = internal.NewWaitForJobsDuration(triggerWaitTime)
+	// workflows are only created after configuration of the new pipeline has been processed
+	opts.RequireWorkflows = true
+
+	result, err := internal.WaitForJobs(ctx, logger, client, opts)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("timed out waiting for pipeline %d after %v", pipeline.Number, triggerTimeout)
+		}
+		return err
+	}
+
+	if result.Failed {
+		sugar.Errorf("one or more workflows or jobs of pipeline %d failed", pipeline.Number)
+		printFailureReport(result)
+		os.Exit(exitCodeFailed)
+	}
+
+	sugar.Infof("all workflows and jobs of pipeline %d finished successfully", pipeline.Number)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(triggerCmd)
+
+	addProjectFlags(triggerCmd)
+	addWorkflowFilterFlags(triggerCmd)
+
+	triggerCmd.Flags().StringVar(&triggerBranch, "branch", "", "branch to trigger the pipeline for, default branch of the project if neither --branch nor --tag is specified")
+	triggerCmd.Flags().StringVar(&triggerTag, "tag", "", "tag to trigger the pipeline for")
+	triggerCmd.Flags().StringArrayVar(&triggerParameters, "parameter", nil, "pipeline parameter as name=value, true/false and integers are passed as booleans and numbers ; can be specified multiple times")
+	triggerCmd.Flags().StringVar(&triggerParametersFile, "parameters-file", "", "YAML or JSON file with a map of pipeline parameters")
+	triggerCmd.Flags().BoolVar(&triggerWait, "wait", false, "wait for workflows of the new pipeline to finish")
+	triggerCmd.Flags().DurationVar(&triggerTimeout, "timeout", time.Hour, "time out for triggering and, with --wait, waiting for the pipeline")
+	triggerCmd.Flags().DurationVar(&triggerWaitTime, "wait-time", 10*time.Second, "time to wait between performing checks with --wait")
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net/url"
 	"os"
 	"time"
 
@@ -17,11 +16,28 @@ import (
 
 var exclude string
 var jobPrefix string
+var includeJobs string
+var excludeJobs string
+var explainFilters bool
+var where string
+var allowFailure string
+var matrixJobs string
+var inferMatrixJobs bool
 var failOnError bool
+var cancelOnFailure bool
+var cancelOnTimeout bool
+var retryFailed int
+var onHold string
+var upstreamOf string
 var failHeader string
 var failFooter string
 var timeout time.Duration
 var waitTime time.Duration
+var verbose bool
+var heartbeatInterval time.Duration
+
+// value of --upstream-of when specified without a job name
+const upstreamOfCurrentJob = "$CIRCLE_JOB"
 
 // waitForJobsCmd represents the waitForJobs command
 var waitForJobsCmd = &cobra.Command{
@@ -37,88 +53,237 @@ circleci-helper wait-for-jobs --token ... --pipeline ... --workflow "myworkflow"
 }
 
 func printWorkflowNameAndURL(workflow *circle.Workflow) {
-	workflowURL := fmt.Sprintf(
-		"https://app.circleci.com/pipelines/%s/%s/%s/%d/workflows/%s",
-		url.PathEscape(projectType), url.PathEscape(org), url.PathEscape(project),
-		pipelineNumber,
-		url.PathEscape(workflow.ID),
-	)
+	workflowURL := internal.WorkflowURL(projectType, org, project, pipelineNumber, workflow.ID)
 	fmt.Printf("  - %s ( %s )\n", workflow.Name, workflowURL)
 }
 
+// printFailureReport prints human-friendly report of failed workflows.
+func printFailureReport(result *internal.WorkflowsSummary) {
+	fmt.Printf(`
+
+##################################################################################################
+
+%s
+
+`,
+		failHeader,
+	)
+
+	// report all workflows that have failed
+	for _, workflow := range result.FailedWorkflows {
+		printWorkflowNameAndURL(workflow.Workflow)
+		printFailedMatrixJobs(workflow)
+	}
+
+	// report any workflow that has at least one job that has failed
+	for _, workflow := range result.PendingWorkflows {
+		if len(workflow.FailedJobs) > 0 {
+			printWorkflowNameAndURL(workflow.Workflow)
+			printFailedMatrixJobs(workflow)
+		}
+	}
+
+	fmt.Printf(`
+
+%s
+
+##################################################################################################
+`,
+		failFooter,
+	)
+}
+
+// printFailedMatrixJobs prints a line for each matrix job of a workflow that has failed variants.
+func printFailedMatrixJobs(workflow *internal.WorkflowDetails) {
+	for _, matrixJob := range workflow.MatrixJobs {
+		if len(matrixJob.FailedVariants) > 0 {
+			fmt.Printf("      %s\n", matrixJob)
+		}
+	}
+}
+
+// cancelRunningWorkflows cancels matching workflows that are still running.
+func cancelRunningWorkflows(logger *zap.Logger, client circle.Client) {
+	// use a separate context as the command's context may have already timed out
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, err := internal.CancelWorkflows(ctx, logger, client, internal.CancelWorkflowsOptions{
+		ProjectType:      projectType,
+		Org:              org,
+		Project:          project,
+		PipelineNumber:   pipelineNumber,
+		WorkflowNames:    commaSeparatedListToSlice(workflow),
+		IncludeWorkflows: commaSeparatedListToSlice(includeWorkflows),
+		ExcludeWorkflows: commaSeparatedListToSlice(excludeWorkflows),
+	})
+	if err != nil {
+		logger.Sugar().Errorf("unable to cancel workflows: %v", err)
+	}
+}
+
+// waitForJobsOptions returns options for waiting for jobs based on flags.
+func waitForJobsOptions() internal.WaitForJobsOptions {
+	return internal.WaitForJobsOptions{
+		ProjectType:       projectType,
+		Org:               org,
+		Project:           project,
+		PipelineNumber:    pipelineNumber,
+		WorkflowNames:     commaSeparatedListToSlice(workflow),
+		ExcludeJobNames:   commaSeparatedListToSlice(exclude),
+		JobPrefixes:       commaSeparatedListToSlice(jobPrefix),
+		IncludeWorkflows:  commaSeparatedListToSlice(includeWorkflows),
+		ExcludeWorkflows:  commaSeparatedListToSlice(excludeWorkflows),
+		IncludeJobs:       commaSeparatedListToSlice(includeJobs),
+		ExcludeJobs:       commaSeparatedListToSlice(excludeJobs),
+		FailOnError:       failOnError || cancelOnFailure,
+		WaitDuration:      internal.NewWaitForJobsDuration(waitTime),
+		RetryFailed:       retryFailed,
+		OnHold:            onHold,
+		UpstreamOf:        upstreamOf,
+		Where:             where,
+		AllowFailure:      commaSeparatedListToSlice(allowFailure),
+		MatrixJobs:        commaSeparatedListToSlice(matrixJobs),
+		InferMatrixJobs:   inferMatrixJobs,
+		Verbose:           verbose,
+		HeartbeatInterval: heartbeatInterval,
+		Concurrency:       concurrency,
+		// jobs of failed workflows are listed in the summary reported to GitHub
+		GetFailedWorkflowJobs: githubReport != "",
+	}
+}
+
+// printFilterExplanations prints which workflows and jobs are kept by filters, without waiting for them.
+func printFilterExplanations(ctx context.Context, client circle.Client) error {
+	explanations, err := internal.ExplainFilters(ctx, client, waitForJobsOptions())
+	if err != nil {
+		return err
+	}
+
+	// describe whether a workflow or a job is included by filters
+	describe := func(explanation *internal.FilterExplanation) string {
+		if explanation.Included {
+			return "included"
+		}
+		return "excluded"
+	}
+
+	for _, workflow := range explanations {
+		fmt.Printf("workflow %s: %s (%s)\n", workflow.Name, describe(&workflow.FilterExplanation), workflow.Rule)
+		for _, job := range workflow.Jobs {
+			fmt.Printf("  - job %s: %s (%s)\n", job.Name, describe(job), job.Rule)
+		}
+	}
+
+	return nil
+}
+
 func waitForJobsMain(logger *zap.Logger, cmd *cobra.Command, args []string) error {
 	if err := validateWorkflowFlags(); err != nil {
 		return err
 	}
 
+	if upstreamOf == upstreamOfCurrentJob {
+		// --upstream-of without a value does not consume the next argument, so catch "--upstream-of myjob"
+		if len(args) > 0 {
+			return fmt.Errorf("unexpected arguments %v, use --upstream-of=<job> to specify the job", args)
+		}
+		upstreamOf = os.Getenv("CIRCLE_JOB")
+		if upstreamOf == "" {
+			return fmt.Errorf("--upstream-of requires a job name when CIRCLE_JOB is not set")
+		}
+	}
+
 	sugar := logger.Sugar()
 
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	client := circle.NewClient(logger, circleAPIToken)
+	notifier := newWebhookNotifier(logger)
 
-	result, err := internal.WaitForJobs(
-		ctx,
-		logger,
-		client,
-		internal.WaitForJobsOptions{
-			ProjectType:     projectType,
-			Org:             org,
-			Project:         project,
-			PipelineNumber:  pipelineNumber,
-			WorkflowNames:   commaSeparatedListToSlice(workflow),
-			ExcludeJobNames: commaSeparatedListToSlice(exclude),
-			JobPrefixes:     commaSeparatedListToSlice(jobPrefix),
-			FailOnError:     failOnError,
-			WaitDuration:    internal.NewWaitForJobsDuration(waitTime),
-		},
-	)
+	if explainFilters {
+		return printFilterExplanations(ctx, client)
+	}
+
+	githubReporter, err := newGitHubReporter(ctx, logger, client)
+	if err != nil {
+		return err
+	}
+	if githubReporter != nil {
+		if err := githubReporter.Start(ctx); err != nil {
+			sugar.Warnf("unable to report pending status to GitHub: %v", err)
+		}
+	}
+
+	// report the outcome to all configured integrations
+	reportOutcome := func(outcome string, result *internal.WorkflowsSummary, resultErr error) {
+		// avoid passing a typed nil pointer so that webhook payloads omit the result
+		if result != nil {
+			notifyWebhooks(logger, notifier, cmd.Name(), outcome, result, resultErr)
+		} else {
+			notifyWebhooks(logger, notifier, cmd.Name(), outcome, nil, resultErr)
+		}
+		if githubReporter != nil {
+			// use a separate context as the command's context may have already timed out
+			if err := githubReporter.Finish(context.Background(), outcome, result); err != nil {
+				sugar.Warnf("unable to report status to GitHub: %v", err)
+			}
+		}
+	}
+
+	result, err := internal.WaitForJobs(ctx, logger, client, waitForJobsOptions())
 	if err != nil {
 		// context's timeout has been exceeded
 		if errors.Is(err, ctx.Err()) {
-			return fmt.Errorf("timed out waiting for jobs after %v", timeout)
+			err = fmt.Errorf("timed out waiting for jobs after %v", timeout)
+			reportOutcome(internal.OutcomeTimeout, nil, err)
+			if cancelOnTimeout {
+				cancelRunningWorkflows(logger, client)
+			}
+			return err
 		}
+		reportOutcome(internal.OutcomeError, nil, err)
 		return err
 	}
 
+	// report jobs allowed to fail separately, as they do not affect the result
+	for _, details := range result.AllWorkflows {
+		grouped := map[string]bool{}
+		for _, matrixJob := range details.MatrixJobs {
+			if matrixJob.AllowedFailed > 0 {
+				sugar.Warnf("%s in workflow %s, some of its jobs are allowed to fail", matrixJob, details.Workflow.Name)
+			}
+			for _, name := range matrixJob.Jobs {
+				grouped[name] = true
+			}
+		}
+		for _, job := range details.AllowedFailedJobs {
+			if !grouped[job.Name] {
+				sugar.Warnf("job %s in workflow %s failed, but is allowed to fail", job.Name, details.Workflow.Name)
+			}
+		}
+	}
+
+	// report flaky jobs regardless of the final result
+	for _, retried := range result.PassedAfterRetryJobs {
+		sugar.Warnf("job %s in workflow %s only passed after %d retries", retried.Job.Name, retried.Workflow.Name, retried.Retries)
+	}
+
 	if !result.Failed {
 		sugar.Infof("all workflows and jobs finished successfully")
+		reportOutcome(internal.OutcomeSuccess, result, nil)
 	} else {
 		sugar.Errorf("one or more workflows or jobs failed")
+		reportOutcome(internal.OutcomeFailure, result, nil)
 		if failOnError {
-			fmt.Printf(`
-
-##################################################################################################
-
-%s
-
-`,
-				failHeader,
-			)
-
-			// report all workflows that have failed
-			for _, workflow := range result.FailedWorkflows {
-				printWorkflowNameAndURL(workflow.Workflow)
-			}
-
-			// report any workflow that has at least one job that has failed
-			for _, workflow := range result.PendingWorkflows {
-				if len(workflow.FailedJobs) > 0 {
-					printWorkflowNameAndURL(workflow.Workflow)
-				}
-			}
-
-			fmt.Printf(`
-
-%s
-
-##################################################################################################
-`,
-				failFooter,
-			)
-
-			os.Exit(2)
+			printFailureReport(result)
+		}
+		if cancelOnFailure {
+			cancelRunningWorkflows(logger, client)
+		}
+		if failOnError {
+			os.Exit(exitCodeFailed)
 		}
 	}
 
@@ -129,12 +294,30 @@ func init() {
 	rootCmd.AddCommand(waitForJobsCmd)
 
 	addWorkflowFlags(waitForJobsCmd)
+	addWebhookFlags(waitForJobsCmd)
+	addGitHubFlags(waitForJobsCmd)
 
 	waitForJobsCmd.Flags().StringVar(&exclude, "exclude", "", "job or jobs to exclude, comma separated list")
 	waitForJobsCmd.Flags().StringVar(&jobPrefix, "job-prefix", "", "job prefix or prefixes to limit filtering to, comma separated list")
+	waitForJobsCmd.Flags().StringVar(&includeJobs, "include-jobs", "", "job patterns to limit to (globs, or regular expressions prefixed with re:), comma separated list")
+	waitForJobsCmd.Flags().StringVar(&excludeJobs, "exclude-jobs", "", "job patterns to exclude (globs, or regular expressions prefixed with re:), comma separated list")
+	waitForJobsCmd.Flags().StringVar(&where, "where", "", `expression selecting workflows and jobs, i.e. 'workflow.name like "deploy-*" and not job.approval'`)
+	waitForJobsCmd.Flags().StringVar(&allowFailure, "allow-failure", "", "job patterns (globs, or regular expressions prefixed with re:) that are waited for, but are allowed to fail, comma separated list")
+	waitForJobsCmd.Flags().StringVar(&matrixJobs, "matrix-jobs", "", "patterns of matrix jobs to report as a single line, such as 'test-<< matrix.go >>-<< matrix.os >>' ; * also matches any text, comma separated list")
+	waitForJobsCmd.Flags().BoolVar(&inferMatrixJobs, "infer-matrix-jobs", false, "report jobs whose names differ in two or more dash separated parts, covering all combinations of their values, as a single matrix job")
+	waitForJobsCmd.Flags().BoolVar(&explainFilters, "explain-filters", false, "print which workflows and jobs are matched by filters and exit without waiting")
 	waitForJobsCmd.Flags().BoolVar(&failOnError, "fail-on-error", false, "print human-friendly details about failed workflows and exit with non-zero exit code")
+	waitForJobsCmd.Flags().BoolVar(&cancelOnFailure, "cancel-on-failure", false, "cancel workflows that are still running as soon as a job has failed")
+	waitForJobsCmd.Flags().BoolVar(&cancelOnTimeout, "cancel-on-timeout", false, "cancel workflows that are still running when timing out waiting for jobs")
+	waitForJobsCmd.Flags().IntVar(&retryFailed, "retry-failed", 0, "rerun failed workflows from failed jobs up to specified number of times")
+	waitForJobsCmd.Flags().StringVar(&onHold, "on-hold", internal.OnHoldWait, "how to handle approval jobs that are on hold: wait, ignore (along with jobs depending on them), fail or approve")
+	waitForJobsCmd.Flags().StringVar(&upstreamOf, "upstream-of", "", "only wait for jobs that specified job depends on, directly or transitively, ignoring workflows that do not contain it ; use --upstream-of=<job> or --upstream-of alone for $CIRCLE_JOB")
+	waitForJobsCmd.Flags().Lookup("upstream-of").NoOptDefVal = upstreamOfCurrentJob
 	waitForJobsCmd.Flags().StringVar(&failHeader, "fail-header", "", "additional message header to print before the report of failed CircleCI workflows")
 	waitForJobsCmd.Flags().StringVar(&failFooter, "fail-footer", "", "additional message footer to print after the report of failed CircleCI workflows")
+	addConcurrencyFlag(waitForJobsCmd)
 	waitForJobsCmd.Flags().DurationVar(&timeout, "timeout", 15*time.Minute, "time out to wait for results")
 	waitForJobsCmd.Flags().DurationVar(&waitTime, "wait-time", 10*time.Second, "time out to wait between performing checks (twice as much if >= 3 jobs are still pending)")
+	waitForJobsCmd.Flags().BoolVar(&verbose, "verbose", false, "log status of all workflows and jobs on every check instead of only logging changes")
+	waitForJobsCmd.Flags().DurationVar(&heartbeatInterval, "heartbeat-interval", internal.DefaultHeartbeatInterval, "how often to log a summary while waiting if nothing has changed")
 }
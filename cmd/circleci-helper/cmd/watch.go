@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"golang.org/x/term"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/internal"
+)
+
+var watchPlain bool
+var watchTimeout time.Duration
+var watchWaitTime time.Duration
+
+// how often the dashboard is redrawn, which also drives the spinner
+const watchRefreshInterval = 100 * time.Millisecond
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Watch workflows and jobs of a pipeline in a live terminal dashboard",
+	Long: `Shows a live view of workflows and jobs of a pipeline, refreshed until all of them finish. For example:
+
+circleci-helper watch --token ... --pipeline-number ... --org ... --project ...
+
+Use up/down arrows (or j/k) to select a job, f to jump to the next failure, enter (or l) to open
+output of the failed steps of the selected job and q to quit. If standard output is not a terminal,
+progress is logged the same way as wait-for-jobs does.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandHelper(cmd, args, watchMain)
+	},
+}
+
+// keys that the dashboard responds to
+const (
+	watchKeyUp = iota + 1
+	watchKeyDown
+	watchKeyEnter
+	watchKeyEscape
+	watchKeyNextFailure
+	watchKeyQuit
+)
+
+// parseWatchKey converts input read from a terminal in raw mode to one of watchKey constants, or 0 if not known.
+func parseWatchKey(input []byte) int {
+	switch string(input) {
+	case "\x1b[A", "\x1bOA", "k":
+		return watchKeyUp
+	case "\x1b[B", "\x1bOB", "j":
+		return watchKeyDown
+	case "\r", "\n", "l":
+		return watchKeyEnter
+	case "\x1b":
+		return watchKeyEscape
+	case "f":
+		return watchKeyNextFailure
+	case "q", "\x03":
+		return watchKeyQuit
+	}
+	return 0
+}
+
+// watchLog is output of failed steps of a job, retrieved in the background.
+type watchLog struct {
+	title string
+	text  string
+	err   error
+}
+
+func watchMain(logger *zap.Logger, cmd *cobra.Command, args []string) error {
+	if err := validateWorkflowFlags(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), watchTimeout)
+	defer cancel()
+
+	client := circle.NewClient(logger, circleAPIToken)
+
+	opts := internal.WaitForJobsOptions{
+		ProjectType:              projectType,
+		Org:                      org,
+		Project:                  project,
+		PipelineNumber:           pipelineNumber,
+		WorkflowNames:            commaSeparatedListToSlice(workflow),
+		IncludeWorkflows:         commaSeparatedListToSlice(includeWorkflows),
+		ExcludeWorkflows:         commaSeparatedListToSlice(excludeWorkflows),
+		IncludeJobs:              commaSeparatedListToSlice(includeJobs),
+		ExcludeJobs:              commaSeparatedListToSlice(excludeJobs),
+		Where:                    where,
+		GetSucceededWorkflowJobs: true,
+		GetFailedWorkflowJobs:    true,
+		WaitDuration:             internal.NewWaitForJobsDuration(watchWaitTime),
+		Concurrency:              concurrency,
+	}
+
+	// fall back to logging progress if the dashboard cannot be shown
+	if watchPlain || !term.IsTerminal(int(os.Stdout.Fd())) || !term.IsTerminal(int(os.Stdin.Fd())) {
+		result, err := internal.WaitForJobs(ctx, logger, client, opts)
+		if err != nil {
+			return err
+		}
+		if result.Failed {
+			logger.Sugar().Errorf("one or more workflows or jobs failed")
+		}
+		return nil
+	}
+
+	return watchDashboard(ctx, client, opts)
+}
+
+// watchDashboard shows workflows and jobs in a dashboard until the user quits.
+func watchDashboard(ctx context.Context, client circle.Client, opts internal.WaitForJobsOptions) error {
+	stdin, stdout := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	state, err := term.MakeRaw(stdin)
+	if err != nil {
+		return err
+	}
+	// switch to alternate screen and hide cursor, restoring both and the terminal's mode when done
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		term.Restore(stdin, state)
+	}()
+
+	// stop the dashboard when the process is signalled, so that the terminal is restored instead of left in raw mode
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// canceling the context stops waiting for jobs, retrieving logs and forwarding keys once the dashboard exits
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan *internal.WorkflowsSummary, 1)
+	finished := make(chan error, 1)
+	opts.OnEvent = func(event internal.Event) {
+		if e, ok := event.(internal.PollCompleted); ok {
+			// only keep the most recent result if the dashboard has not picked up the previous one yet
+			select {
+			case <-results:
+			default:
+			}
+			results <- e.Result
+		}
+	}
+	go func() {
+		// logs would break the dashboard, so progress is only shown through events
+		_, err := internal.WaitForJobs(ctx, zap.NewNop(), client, opts)
+		finished <- err
+	}()
+
+	// reading from stdin cannot be interrupted, so the reader only exits on the next key or read error after the dashboard exits
+	keys := make(chan int)
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				return
+			}
+			key := parseWatchKey(buf[:n])
+			if key == 0 {
+				continue
+			}
+			select {
+			case keys <- key:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	logs := make(chan watchLog, 1)
+	dashboard := internal.NewDashboard(fmt.Sprintf("%s/%s pipeline %d", org, project, pipelineNumber))
+	dashboard.SetMessage("loading workflows...")
+
+	ticker := time.NewTicker(watchRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		width, height, err := term.GetSize(stdout)
+		if err != nil {
+			width, height = 80, 24
+		}
+		lines := dashboard.Render(time.Now(), width, height, true)
+		fmt.Print("\x1b[H" + strings.Join(lines, "\x1b[K\r\n") + "\x1b[K\x1b[J")
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("timed out watching workflows after %v", watchTimeout)
+			}
+			return nil
+		case <-ticker.C:
+			dashboard.Tick()
+		case result := <-results:
+			dashboard.Update(result)
+			switch {
+			case result.Finished && result.Failed:
+				dashboard.SetMessage("all workflows finished - failed")
+			case result.Finished:
+				dashboard.SetMessage("all workflows finished - successfully")
+			default:
+				dashboard.SetMessage("last refreshed at %s", time.Now().Format("15:04:05"))
+			}
+		case err := <-finished:
+			if err != nil {
+				dashboard.SetMessage("unable to retrieve workflows: %v", err)
+			}
+		case log := <-logs:
+			if log.err != nil {
+				dashboard.SetMessage("unable to retrieve output of %s: %v", log.title, log.err)
+			} else {
+				dashboard.ShowLog(log.title, log.text)
+			}
+		case key := <-keys:
+			switch key {
+			case watchKeyQuit:
+				if !dashboard.LogVisible() {
+					return nil
+				}
+				dashboard.CloseLog()
+			case watchKeyEscape:
+				dashboard.CloseLog()
+			case watchKeyUp:
+				dashboard.MoveSelection(-1)
+			case watchKeyDown:
+				dashboard.MoveSelection(1)
+			case watchKeyNextFailure:
+				if !dashboard.SelectNextFailure() {
+					dashboard.SetMessage("no failed workflows or jobs")
+				}
+			case watchKeyEnter:
+				workflow, job := dashboard.Selected()
+				if job == nil || dashboard.LogVisible() {
+					continue
+				}
+				dashboard.SetMessage("retrieving output of %s...", job.Name)
+				go func() {
+					log := fetchWatchLog(ctx, client, workflow, job)
+					select {
+					case logs <- log:
+					case <-ctx.Done():
+					}
+				}()
+			}
+		}
+	}
+}
+
+// watchLogMaxLines is the maximum number of last lines of output of each failed step shown in the log view.
+const watchLogMaxLines = 10000
+
+// fetchWatchLog retrieves output of failed steps of a job.
+func fetchWatchLog(ctx context.Context, client circle.Client, workflow *circle.Workflow, job *circle.Job) watchLog {
+	title := fmt.Sprintf("job %s in workflow %s", job.Name, workflow.Name)
+	// the log view can be scrolled, so whole output is shown instead of an excerpt, only keeping its last lines in memory
+	failures, err := internal.GetJobFailures(ctx, client, projectType, org, project, workflow, job, internal.JobOutputOptions{
+		Limits: internal.OutputLimits{MaxLines: watchLogMaxLines, TailOnly: true},
+	})
+	if err != nil {
+		return watchLog{title: title, err: err}
+	}
+	if len(failures) == 0 {
+		return watchLog{title: title, err: fmt.Errorf("no failed steps")}
+	}
+
+	var sb strings.Builder
+	for _, failure := range failures {
+		fmt.Fprintf(&sb, "==> step %s action %s", failure.StepName, failure.ActionName)
+		if failure.Action != nil && failure.Action.Parallel {
+			fmt.Fprintf(&sb, " on %s", internal.DescribeFailedNode(failure.Action))
+		}
+		fmt.Fprintf(&sb, "\n%s\n", failure.Messages)
+	}
+	return watchLog{title: title, text: sb.String()}
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	addWorkflowFlags(watchCmd)
+
+	watchCmd.Flags().StringVar(&includeJobs, "include-jobs", "", "job patterns to limit to (globs, or regular expressions prefixed with re:), comma separated list")
+	watchCmd.Flags().StringVar(&excludeJobs, "exclude-jobs", "", "job patterns to exclude (globs, or regular expressions prefixed with re:), comma separated list")
+	watchCmd.Flags().StringVar(&where, "where", "", `expression selecting workflows and jobs, i.e. 'workflow.name like "deploy-*" and not job.approval'`)
+	watchCmd.Flags().BoolVar(&watchPlain, "plain", false, "log progress instead of showing the dashboard, which is the default if standard output is not a terminal")
+	addConcurrencyFlag(watchCmd)
+	watchCmd.Flags().DurationVar(&watchTimeout, "timeout", time.Hour, "time out to watch for")
+	watchCmd.Flags().DurationVar(&watchWaitTime, "wait-time", 10*time.Second, "time to wait between refreshing workflows and jobs")
+}
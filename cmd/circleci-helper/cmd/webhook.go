@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/internal"
+)
+
+// common flags and variables for commands that can send webhook notifications
+var webhookURLs string
+var webhookSecret string
+var webhookOn string
+var webhookRetries int
+var webhookTimeout time.Duration
+
+// addWebhookFlags adds flags for configuring webhook notifications to the given command.
+func addWebhookFlags(command *cobra.Command) {
+	command.Flags().StringVar(&webhookURLs, "webhook-url", "", "URL or URLs to POST JSON results to, comma separated list")
+	command.Flags().StringVar(&webhookSecret, "webhook-secret", "", "secret used to sign webhook payloads with HMAC-SHA256 (can also be set as webhook-secret in config file or CIRCLECI_HELPER_WEBHOOK_SECRET environment variable)")
+	command.Flags().StringVar(&webhookOn, "webhook-on", "always", "outcomes to send webhooks for (success, failure, timeout, error or always), comma separated list")
+	command.Flags().IntVar(&webhookRetries, "webhook-retries", 3, "number of retries when sending a webhook fails")
+	command.Flags().DurationVar(&webhookTimeout, "webhook-timeout", 10*time.Second, "time out for a single attempt to send a webhook")
+
+	// allow specifying the secret in config file or as environment variable, so that it does not show up in process listings ;
+	// the flag is not bound as it is added to multiple commands and only one of them can be bound
+	cobra.CheckErr(viper.BindEnv("webhook-secret", "CIRCLECI_HELPER_WEBHOOK_SECRET"))
+}
+
+// newWebhookNotifier creates a notifier based on webhook flags.
+func newWebhookNotifier(logger *zap.Logger) *internal.WebhookNotifier {
+	secret := webhookSecret
+	if secret == "" {
+		secret = viper.GetString("webhook-secret")
+	}
+
+	return internal.NewWebhookNotifier(logger, internal.WebhookOptions{
+		URLs:       commaSeparatedListToSlice(webhookURLs),
+		Secret:     secret,
+		Outcomes:   commaSeparatedListToSlice(webhookOn),
+		Retries:    webhookRetries,
+		Timeout:    webhookTimeout,
+		RetryDelay: time.Second,
+	})
+}
+
+// notifyWebhooks sends outcome of a command to webhooks, logging any errors as failing to notify should not fail the command.
+func notifyWebhooks(logger *zap.Logger, notifier *internal.WebhookNotifier, command string, outcome string, result interface{}, resultErr error) {
+	payload := &internal.WebhookPayload{
+		Command:        command,
+		Outcome:        outcome,
+		ProjectType:    projectType,
+		Org:            org,
+		Project:        project,
+		PipelineNumber: pipelineNumber,
+		Result:         result,
+	}
+	if resultErr != nil {
+		payload.Error = resultErr.Error()
+	}
+
+	// use a separate context as the command's context may have already timed out
+	if err := notifier.Notify(context.Background(), payload); err != nil {
+		logger.Sugar().Warnf("unable to send webhook notifications: %v", err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/internal"
 )
 
 // common flags and variables for workflow-related commands
@@ -12,24 +14,52 @@ var projectType string
 var org string
 var project string
 var workflow string
+var includeWorkflows string
+var excludeWorkflows string
+var concurrency int
 
-// addWorkflowFlags adds common workflow-related flags to the given command.
-func addWorkflowFlags(command *cobra.Command) {
-	command.Flags().IntVar(&pipelineNumber, "pipeline-number", 0, "pipeline number")
+// addProjectFlags adds flags identifying a project to the given command.
+func addProjectFlags(command *cobra.Command) {
 	command.Flags().StringVar(&projectType, "project-type", "github", "project type (i.e. github)")
 	command.Flags().StringVar(&org, "org", "", "organization")
 	command.Flags().StringVar(&project, "project", "", "project")
+}
+
+// addWorkflowFilterFlags adds flags for limiting workflows to the given command.
+func addWorkflowFilterFlags(command *cobra.Command) {
 	command.Flags().StringVar(&workflow, "workflow", "", "workflow names to limit to, comma separated list")
+	command.Flags().StringVar(&includeWorkflows, "include-workflows", "", "workflow patterns to limit to (globs, or regular expressions prefixed with re:), comma separated list")
+	command.Flags().StringVar(&excludeWorkflows, "exclude-workflows", "", "workflow patterns to exclude (globs, or regular expressions prefixed with re:), comma separated list")
 }
 
-// validateWorkflowFlags validates flags common for workflow-related commands.
-func validateWorkflowFlags() error {
+// addWorkflowFlags adds common workflow-related flags to the given command.
+func addWorkflowFlags(command *cobra.Command) {
+	command.Flags().IntVar(&pipelineNumber, "pipeline-number", 0, "pipeline number")
+	addProjectFlags(command)
+	addWorkflowFilterFlags(command)
+}
+
+// addConcurrencyFlag adds a flag limiting how many CircleCI API requests are made in parallel to the given command.
+func addConcurrencyFlag(command *cobra.Command) {
+	command.Flags().IntVar(&concurrency, "concurrency", internal.DefaultConcurrency, "number of requests to CircleCI, such as retrieving workflows, jobs or artifacts, to make in parallel")
+}
+
+// validateProjectFlags validates flags identifying a project.
+func validateProjectFlags() error {
 	if org == "" {
 		return fmt.Errorf("org must be specified")
 	}
 	if project == "" {
 		return fmt.Errorf("project must be specified")
 	}
+	return nil
+}
+
+// validateWorkflowFlags validates flags common for workflow-related commands.
+func validateWorkflowFlags() error {
+	if err := validateProjectFlags(); err != nil {
+		return err
+	}
 	if pipelineNumber == 0 {
 		return fmt.Errorf("pipeline-number must be specified")
 	}
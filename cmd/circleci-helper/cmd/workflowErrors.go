@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
 	"github.com/influxdata/circleci-helper/cmd/circleci-helper/internal"
@@ -10,6 +12,16 @@ import (
 	"go.uber.org/zap"
 )
 
+var workflowErrorsFullOutput bool
+var workflowErrorsTailLines int
+var workflowErrorsContextLines int
+var workflowErrorsPatterns []string
+var workflowErrorsMaxOutputBytes int64
+var workflowErrorsMaxOutputLines int
+var workflowErrorsOutputTailOnly bool
+var workflowErrorsNode int
+var workflowErrorsTimeout time.Duration
+
 // workflowErrorsCmd represents the workflow-errors command
 var workflowErrorsCmd = &cobra.Command{
 	Use:   "workflow-errors",
@@ -17,36 +29,118 @@ var workflowErrorsCmd = &cobra.Command{
 	Long: `Reports all errors for specified workflow. For example:
 
 circleci-helper workflow-errors --token ... --pipeline ... --workflow "myworkflow" --project-type ...
+
+Only excerpts of output of failed steps are reported - their last lines along with lines around Go test failures
+and panics, compiler errors, npm and pytest failures and lines matching --error-pattern. Use --full-output
+to report whole output instead, up to --max-output-bytes and --max-output-lines.
+
+For jobs with parallelism, failures of each step are reported together, listing nodes that failed along with
+their exit codes. Use --node to only report failures on a single node.
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		commandHelper(cmd, args, workflowErrorsMain)
 	},
 }
 
+// failureMessages returns output of a failed action for printing.
+func failureMessages(failure *internal.WorkflowErrorsFailure) string {
+	switch {
+	case failure.Messages == "":
+		return "(no output)"
+	case failure.OutputTruncated:
+		return failure.Messages + "\n(output truncated)"
+	default:
+		return failure.Messages
+	}
+}
+
+// printStepFailures prints output of a failed step ; for jobs with parallelism, output of each failed node is printed.
+func printStepFailures(step *internal.StepFailures) {
+	if !step.Parallel() {
+		for _, failure := range step.Failures {
+			fmt.Printf("Failed to run workflow %s job %s at step %s action %s:\n%s\n\n", failure.Workflow.Name, failure.Job.Name, failure.StepName, failure.ActionName, failureMessages(failure))
+		}
+	} else {
+		fmt.Printf("Failed to run workflow %s job %s at step %s on %s:\n", step.Workflow.Name, step.Job.Name, step.StepName, step.DescribeNodes())
+		for _, failure := range step.Failures {
+			fmt.Printf("==> %s\n%s\n\n", internal.DescribeFailedNode(failure.Action), failureMessages(failure))
+		}
+	}
+
+	for _, failure := range step.Failures {
+		if len(failure.FailedTests) == 0 {
+			continue
+		}
+		fmt.Printf("Failed tests:\n")
+		for _, test := range failure.FailedTests {
+			fmt.Printf("  - %s\n", describeTest(test.Classname, test.Name, test.File))
+			if test.Message != "" {
+				fmt.Printf("%s\n", indentLines(test.Message, "      "))
+			}
+		}
+		fmt.Printf("\n")
+	}
+	fmt.Printf("----\n")
+}
+
 func workflowErrorsMain(logger *zap.Logger, cmd *cobra.Command, args []string) error {
 	if err := validateWorkflowFlags(); err != nil {
 		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	var nodes []int
+	if workflowErrorsNode >= 0 {
+		nodes = []int{workflowErrorsNode}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), workflowErrorsTimeout)
 	defer cancel()
 
 	client := circle.NewClient(logger, circleAPIToken)
+	notifier := newWebhookNotifier(logger)
 
 	result, err := internal.WorkflowErrors(ctx, logger, client, internal.WorkflowErrorsOptions{
-		ProjectType:    projectType,
-		Org:            org,
-		Project:        project,
-		PipelineNumber: pipelineNumber,
-		WorkflowNames:  commaSeparatedListToSlice(workflow),
+		ProjectType:      projectType,
+		Org:              org,
+		Project:          project,
+		PipelineNumber:   pipelineNumber,
+		WorkflowNames:    commaSeparatedListToSlice(workflow),
+		IncludeWorkflows: commaSeparatedListToSlice(includeWorkflows),
+		ExcludeWorkflows: commaSeparatedListToSlice(excludeWorkflows),
+		FullOutput:       workflowErrorsFullOutput,
+		Excerpt: internal.ExcerptOptions{
+			TailLines:    workflowErrorsTailLines,
+			ContextLines: workflowErrorsContextLines,
+			Patterns:     workflowErrorsPatterns,
+		},
+		OutputLimits: internal.OutputLimits{
+			MaxBytes: workflowErrorsMaxOutputBytes,
+			MaxLines: workflowErrorsMaxOutputLines,
+			TailOnly: workflowErrorsOutputTailOnly,
+		},
+		Nodes:       nodes,
+		Concurrency: concurrency,
 	})
 
 	if err != nil {
+		// context's timeout has been exceeded
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out retrieving errors after %v", workflowErrorsTimeout)
+			notifyWebhooks(logger, notifier, cmd.Name(), internal.OutcomeTimeout, nil, err)
+			return err
+		}
+		notifyWebhooks(logger, notifier, cmd.Name(), internal.OutcomeError, nil, err)
 		return err
 	}
 
-	for _, failure := range result.Failures {
-		fmt.Printf("Failed to run workflow %s job %s at step %s action %s:\n%s\n\n----\n", failure.Workflow.Name, failure.Job.Name, failure.StepName, failure.ActionName, failure.Messages)
+	if len(result.Failures) > 0 {
+		notifyWebhooks(logger, notifier, cmd.Name(), internal.OutcomeFailure, result, nil)
+	} else {
+		notifyWebhooks(logger, notifier, cmd.Name(), internal.OutcomeSuccess, result, nil)
+	}
+
+	for _, step := range internal.GroupFailuresByStep(result.Failures) {
+		printStepFailures(step)
 	}
 
 	return nil
@@ -56,4 +150,16 @@ func init() {
 	rootCmd.AddCommand(workflowErrorsCmd)
 
 	addWorkflowFlags(workflowErrorsCmd)
+	addWebhookFlags(workflowErrorsCmd)
+	addConcurrencyFlag(workflowErrorsCmd)
+
+	workflowErrorsCmd.Flags().BoolVar(&workflowErrorsFullOutput, "full-output", false, "report whole output of failed steps instead of excerpts")
+	workflowErrorsCmd.Flags().IntVar(&workflowErrorsTailLines, "tail-lines", internal.DefaultExcerptTailLines, "number of last lines of output of failed steps to report")
+	workflowErrorsCmd.Flags().IntVar(&workflowErrorsContextLines, "context-lines", internal.DefaultExcerptContextLines, "number of lines to report before and after lines describing errors")
+	workflowErrorsCmd.Flags().IntVar(&workflowErrorsNode, "node", -1, "only report failures on the node with this index, for jobs with parallelism")
+	workflowErrorsCmd.Flags().Int64Var(&workflowErrorsMaxOutputBytes, "max-output-bytes", 10<<20, "with --full-output, maximum number of bytes of output of each failed step, 0 for no limit")
+	workflowErrorsCmd.Flags().IntVar(&workflowErrorsMaxOutputLines, "max-output-lines", 0, "with --full-output, maximum number of lines of output of each failed step, 0 for no limit")
+	workflowErrorsCmd.Flags().BoolVar(&workflowErrorsOutputTailOnly, "output-tail-only", false, "with --full-output, report the last bytes and lines of output instead of the first ones")
+	workflowErrorsCmd.Flags().StringArrayVar(&workflowErrorsPatterns, "error-pattern", nil, "regular expression matching additional lines describing errors ; can be specified multiple times")
+	workflowErrorsCmd.Flags().DurationVar(&workflowErrorsTimeout, "timeout", 15*time.Minute, "time out for retrieving errors")
 }
@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+)
+
+// policies for handling approval jobs that are on hold
+const (
+	// OnHoldWait waits for approval jobs to be approved.
+	OnHoldWait = "wait"
+	// OnHoldIgnore ignores approval jobs and all jobs that depend on them.
+	OnHoldIgnore = "ignore"
+	// OnHoldFail treats approval jobs that are on hold as failed.
+	OnHoldFail = "fail"
+	// OnHoldApprove approves approval jobs that are on hold.
+	OnHoldApprove = "approve"
+)
+
+// validateOnHoldPolicy returns an error if specified policy for approval jobs is not valid.
+func validateOnHoldPolicy(policy string) error {
+	switch policy {
+	case "", OnHoldWait, OnHoldIgnore, OnHoldFail, OnHoldApprove:
+		return nil
+	}
+	return fmt.Errorf("invalid on hold policy %q, must be one of: %s, %s, %s, %s", policy, OnHoldWait, OnHoldIgnore, OnHoldFail, OnHoldApprove)
+}
+
+// approvalHandler applies policy for approval jobs that are on hold.
+type approvalHandler struct {
+	policy string
+	// approval request IDs that were already approved
+	approved map[string]bool
+	// IDs of approval jobs that cannot be approved as CircleCI did not provide their approval request ID
+	unapprovable map[string]bool
+}
+
+func newApprovalHandler(policy string) *approvalHandler {
+	return &approvalHandler{
+		policy:       policy,
+		approved:     map[string]bool{},
+		unapprovable: map[string]bool{},
+	}
+}
+
+// handleApprovals updates result based on the policy, approving jobs if requested.
+func (h *approvalHandler) handleApprovals(ctx context.Context, logger *zap.Logger, client circle.Client, result *WorkflowsSummary) error {
+	sugar := logger.Sugar()
+
+	if h.policy == "" || h.policy == OnHoldWait {
+		return nil
+	}
+
+	changed := false
+	for _, details := range result.PendingWorkflows {
+		var pendingJobs []*circle.Job
+		onHold := map[string]bool{}
+		for _, job := range details.PendingJobs {
+			if circle.JobAwaitingApproval(job) {
+				onHold[job.ID] = true
+			} else {
+				pendingJobs = append(pendingJobs, job)
+			}
+		}
+
+		if len(onHold) == 0 {
+			continue
+		}
+
+		for _, job := range details.PendingJobs {
+			if !onHold[job.ID] {
+				continue
+			}
+
+			switch h.policy {
+			case OnHoldIgnore:
+				sugar.Infof("ignoring approval job %s in workflow %s and jobs that depend on it", job.Name, details.Workflow.Name)
+			case OnHoldFail:
+				sugar.Warnf("approval job %s in workflow %s is on hold, treating it as failed", job.Name, details.Workflow.Name)
+				details.FailedJobs = append(details.FailedJobs, job)
+			case OnHoldApprove:
+				if h.approved[job.ApprovalRequestID] || h.unapprovable[job.ID] {
+					continue
+				}
+				if job.ApprovalRequestID == "" {
+					// only log once, the job is still waited for as it may be approved manually
+					sugar.Errorf("unable to approve job %s in workflow %s: approval request ID is not known", job.Name, details.Workflow.Name)
+					h.unapprovable[job.ID] = true
+					continue
+				}
+				sugar.Warnf("approving job %s in workflow %s", job.Name, details.Workflow.Name)
+				if err := client.ApproveJob(ctx, details.Workflow.ID, job.ApprovalRequestID); err != nil {
+					return err
+				}
+				h.approved[job.ApprovalRequestID] = true
+			}
+		}
+
+		if h.policy == OnHoldIgnore || h.policy == OnHoldFail {
+			// jobs that depend on an approval job will not run until it is approved, so stop waiting for them
+			dependents := downstreamJobIDs(details.PendingJobs, onHold)
+			details.PendingJobs = nil
+			for _, job := range pendingJobs {
+				if !dependents[job.ID] {
+					details.PendingJobs = append(details.PendingJobs, job)
+				}
+			}
+			changed = true
+		}
+		if h.policy == OnHoldFail {
+			result.Failed = true
+		}
+	}
+
+	if changed {
+		// the result is finished if none of the workflows have any pending jobs left
+		result.Finished = true
+		for _, details := range result.PendingWorkflows {
+			if len(details.PendingJobs) > 0 {
+				result.Finished = false
+			}
+		}
+	}
+
+	return nil
+}
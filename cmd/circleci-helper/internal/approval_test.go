@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+)
+
+func newMockCircleClientWithApproval() *mockCircleClient {
+	return newMockCircleClientWithApprovalInWorkflow("on_hold")
+}
+
+// newMockCircleClientWithApprovalInWorkflow returns a client with a workflow with an approval job on hold ; if the
+// workflow is failing, it also has a job that has already failed.
+func newMockCircleClientWithApprovalInWorkflow(workflowStatus string) *mockCircleClient {
+	m := newMockCircleClient("github", "influxdata", "testproject")
+	m.addPipeline(123, "456")
+	m.addWorkflows("456", []*circle.Workflow{
+		{ID: "456-1", Name: "deploy", Status: workflowStatus, CreatedAt: "2021-01-01T00:00:00.000Z"},
+	})
+	m.addJobs("456-1", []*circle.Job{
+		{ID: "456-1-1", Name: "build", Status: "success", Type: "build"},
+		{ID: "456-1-2", Name: "hold", Status: "on_hold", Type: "approval", ApprovalRequestID: "456-1-2", Dependencies: []string{"456-1-1"}},
+		{ID: "456-1-3", Name: "deploy", Status: "blocked", Type: "build", Dependencies: []string{"456-1-2"}},
+		{ID: "456-1-4", Name: "notify", Status: "blocked", Type: "build", Dependencies: []string{"456-1-3"}},
+	})
+	if workflowStatus == "failing" {
+		m.jobsMap["456-1"] = append(m.jobsMap["456-1"], &circle.Job{ID: "456-1-5", Name: "lint", Status: "failed", Type: "build"})
+	}
+	return m
+}
+
+func Test_WaitForJobs_onHold(t *testing.T) {
+	for _, test := range []struct {
+		policy           string
+		workflowStatus   string
+		expectedFailed   bool
+		expectedApproved int
+	}{
+		{policy: OnHoldIgnore, workflowStatus: "on_hold"},
+		{policy: OnHoldFail, workflowStatus: "on_hold", expectedFailed: true},
+		{policy: OnHoldApprove, workflowStatus: "on_hold", expectedApproved: 1},
+		// approval jobs are also handled in workflows where other jobs have already failed
+		{policy: OnHoldIgnore, workflowStatus: "failing", expectedFailed: true},
+		{policy: OnHoldFail, workflowStatus: "failing", expectedFailed: true},
+		{policy: OnHoldApprove, workflowStatus: "failing", expectedFailed: true, expectedApproved: 1},
+	} {
+		t.Run(test.policy+" "+test.workflowStatus, func(tt *testing.T) {
+			m := newMockCircleClientWithApprovalInWorkflow(test.workflowStatus)
+			result, err := WaitForJobs(context.Background(), zap.NewNop(), m, WaitForJobsOptions{
+				ProjectType:    "github",
+				Org:            "influxdata",
+				Project:        "testproject",
+				PipelineNumber: 123,
+				OnHold:         test.policy,
+				WaitDuration:   NewWaitForJobsDuration(time.Millisecond),
+			})
+			if err != nil {
+				tt.Fatalf("unexpected error: %v", err)
+			}
+
+			if want, got := true, result.Finished; want != got {
+				tt.Errorf("invalid value for Finished; want %v, got %v", want, got)
+			}
+			if want, got := test.expectedFailed, result.Failed; want != got {
+				tt.Errorf("invalid value for Failed; want %v, got %v", want, got)
+			}
+			if want, got := test.expectedApproved, len(m.approved); want != got {
+				tt.Errorf("invalid number of approved jobs; want %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func Test_WaitForJobs_invalidOnHold(t *testing.T) {
+	m := newMockCircleClientWithApproval()
+	_, err := WaitForJobs(context.Background(), zap.NewNop(), m, WaitForJobsOptions{OnHold: "invalid"})
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func Test_approvalHandler_unknownApprovalRequestID(t *testing.T) {
+	m := newMockCircleClientWithApproval()
+	workflow := m.workflowsMap["456"][0]
+	hold := &circle.Job{ID: "456-1-2", Name: "hold", Status: "on_hold", Type: "approval"}
+	result := &WorkflowsSummary{
+		PendingWorkflows: []*WorkflowDetails{{Workflow: workflow, PendingJobs: []*circle.Job{hold}}},
+	}
+
+	// jobs without an approval request ID are not approved, but still waited for
+	h := newApprovalHandler(OnHoldApprove)
+	for i := 0; i < 2; i++ {
+		if err := h.handleApprovals(context.Background(), zap.NewNop(), m, result); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(m.approved) != 0 {
+		t.Errorf("expected no jobs to be approved, got %v", m.approved)
+	}
+	if want, got := 1, len(result.PendingWorkflows[0].PendingJobs); want != got {
+		t.Errorf("invalid number of pending jobs; want %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,255 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// DownloadArtifactsOptions allows passing options for downloading artifacts of one or more jobs.
+type DownloadArtifactsOptions struct {
+	ProjectType      string
+	Org              string
+	Project          string
+	PipelineNumber   int
+	WorkflowNames    []string
+	IncludeWorkflows []string
+	ExcludeWorkflows []string
+	IncludeJobs      []string
+	ExcludeJobs      []string
+	// IncludePaths and ExcludePaths are patterns of artifact paths to download ; globs without a slash
+	// are also matched against the file name, so that *.tar.gz matches artifacts in any directory.
+	IncludePaths []string
+	ExcludePaths []string
+	// TargetDir is the directory that artifacts are written to, as <job name>/<node index>/<artifact path>.
+	TargetDir string
+	// Concurrency is the number of artifacts downloaded in parallel, DefaultConcurrency if not set.
+	Concurrency int
+	// MaxSize is the maximum size of a single artifact in bytes, if set.
+	MaxSize int64
+	// DryRun only lists artifacts that would be downloaded.
+	DryRun bool
+}
+
+// DownloadedArtifact describes an artifact that was downloaded.
+type DownloadedArtifact struct {
+	Workflow *circle.Workflow `json:"workflow"`
+	Job      *circle.Job      `json:"job"`
+	Artifact *circle.Artifact `json:"artifact"`
+	File     string           `json:"file"`
+	Size     int64            `json:"size"`
+}
+
+// DownloadArtifacts downloads artifacts of finished jobs matching criteria, returning artifacts sorted by their file names.
+func DownloadArtifacts(ctx context.Context, logger *zap.Logger, client circle.Client, opts DownloadArtifactsOptions) ([]*DownloadedArtifact, error) {
+	sugar := logger.Sugar()
+
+	workflowFilter, err := newWorkflowFilter(opts.WorkflowNames, opts.IncludeWorkflows, opts.ExcludeWorkflows)
+	if err != nil {
+		return nil, err
+	}
+
+	jobFilter, err := newJobFilter(nil, nil, opts.IncludeJobs, opts.ExcludeJobs)
+	if err != nil {
+		return nil, err
+	}
+
+	pathFilter, err := newArtifactPathFilter(opts.IncludePaths, opts.ExcludePaths)
+	if err != nil {
+		return nil, err
+	}
+
+	pipelineID, err := client.GetPipelineID(ctx, opts.ProjectType, opts.Org, opts.Project, opts.PipelineNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := checkWorkflowsStatus(
+		ctx, client, pipelineID,
+		checkWorkflowStatusOpts{
+			filterWorkflow:      filterWorkflowByName(workflowFilter, nil),
+			filterJob:           filterJobByName(jobFilter, nil),
+			succeededJobDetails: true,
+			failedJobDetails:    true,
+			// jobs of running workflows are needed as well, such as for a job that needs artifacts of earlier jobs in its workflow
+			pendingJobDetails: true,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// list all artifacts before downloading anything, so that conflicting or invalid paths do not cause partial downloads
+	var result []*DownloadedArtifact
+	files := map[string]*DownloadedArtifact{}
+	for _, workflow := range status.AllWorkflows {
+		// artifacts are only listed for finished jobs, as running jobs may not have uploaded all of them yet
+		jobs := append(append([]*circle.Job{}, workflow.SucceededJobs...), workflow.FailedJobs...)
+		for _, job := range jobs {
+			// approval jobs do not have a job number and cannot store artifacts
+			if job.JobNumber == 0 {
+				continue
+			}
+
+			artifacts, err := client.GetJobArtifacts(ctx, opts.ProjectType, opts.Org, opts.Project, job.JobNumber)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, artifact := range artifacts {
+				if !pathFilter(artifact.Path) {
+					continue
+				}
+
+				file, err := artifactTargetPath(opts.TargetDir, job.Name, artifact)
+				if err != nil {
+					return nil, err
+				}
+
+				if existing, ok := files[file]; ok {
+					return nil, fmt.Errorf(
+						"artifact %s of job %s in workflows %s and %s would be written to the same file",
+						artifact.Path, job.Name, existing.Workflow.Name, workflow.Workflow.Name,
+					)
+				}
+
+				downloaded := &DownloadedArtifact{Workflow: workflow.Workflow, Job: job, Artifact: artifact, File: file}
+				files[file] = downloaded
+				result = append(result, downloaded)
+			}
+		}
+	}
+
+	sort.Slice(result, func(a, b int) bool {
+		return result[a].File < result[b].File
+	})
+
+	if opts.DryRun {
+		for _, artifact := range result {
+			sugar.Infof("would download %s of job %s to %s", artifact.Artifact.Path, artifact.Job.Name, artifact.File)
+		}
+		return result, nil
+	}
+
+	sugar.Infof("downloading %d artifacts to %s", len(result), opts.TargetDir)
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(concurrency)
+	for _, artifact := range result {
+		group.Go(func() error {
+			size, err := downloadArtifact(groupCtx, client, artifact.Artifact, artifact.File, opts.MaxSize)
+			if err != nil {
+				return fmt.Errorf("unable to download %s of job %s: %w", artifact.Artifact.Path, artifact.Job.Name, err)
+			}
+			artifact.Size = size
+			sugar.Infof("downloaded %s of job %s (%d bytes)", artifact.Artifact.Path, artifact.Job.Name, size)
+			return nil
+		})
+	}
+
+	return result, group.Wait()
+}
+
+// newArtifactPathFilter returns a function that checks if an artifact path matches include patterns, if any, and
+// does not match any of the exclude patterns ; globs without a slash are also matched against the file name.
+func newArtifactPathFilter(include []string, exclude []string) (func(artifactPath string) bool, error) {
+	f, err := NewNameFilter(include, exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	matchesAny := func(patterns []*NamePattern, artifactPath string) bool {
+		for _, p := range patterns {
+			if p.Match(artifactPath) {
+				return true
+			}
+			if !strings.HasPrefix(p.String(), regexPatternPrefix) && !strings.Contains(p.String(), "/") && p.Match(path.Base(artifactPath)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(artifactPath string) bool {
+		if len(f.Include) > 0 && !matchesAny(f.Include, artifactPath) {
+			return false
+		}
+		return !matchesAny(f.Exclude, artifactPath)
+	}, nil
+}
+
+// artifactTargetPath returns path that an artifact of a job is written to, ensuring it is inside targetDir.
+func artifactTargetPath(targetDir string, jobName string, artifact *circle.Artifact) (string, error) {
+	relative := filepath.Join(jobName, strconv.Itoa(artifact.NodeIndex), filepath.FromSlash(artifact.Path))
+	if !filepath.IsLocal(relative) {
+		return "", fmt.Errorf("artifact %s of job %s would be written outside of target directory", artifact.Path, jobName)
+	}
+	return filepath.Join(targetDir, relative), nil
+}
+
+// downloadArtifact writes contents of an artifact to file, returning number of bytes written ; contents are written
+// to a temporary file first, so that incomplete downloads do not leave partial files behind.
+func downloadArtifact(ctx context.Context, client circle.Client, artifact *circle.Artifact, file string, maxSize int64) (int64, error) {
+	reader, size, err := client.DownloadArtifact(ctx, artifact)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	if maxSize > 0 && size > maxSize {
+		return 0, fmt.Errorf("artifact size %d exceeds maximum size %d", size, maxSize)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return 0, err
+	}
+
+	temporaryFile := file + ".download"
+	out, err := os.Create(temporaryFile)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(temporaryFile)
+
+	// read at most one byte more than the maximum size to detect artifacts that are too large if their size was not known
+	var source io.Reader = reader
+	if maxSize > 0 {
+		source = io.LimitReader(reader, maxSize+1)
+	}
+
+	written, err := io.Copy(out, source)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if maxSize > 0 && written > maxSize {
+		return 0, fmt.Errorf("artifact exceeds maximum size %d", maxSize)
+	}
+	if size >= 0 && written != size {
+		return 0, fmt.Errorf("incomplete download, expected %d bytes, got %d", size, written)
+	}
+
+	if err := os.Rename(temporaryFile, file); err != nil {
+		return 0, err
+	}
+
+	return written, nil
+}
@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+)
+
+func newMockCircleClientWithArtifacts() *mockCircleClient {
+	m := newMockCircleClient("github", "influxdata", "testproject")
+	m.addPipeline(123, "456")
+	m.addWorkflows("456", []*circle.Workflow{
+		{ID: "456-1", Name: "build", Status: "success", CreatedAt: "2021-01-01T00:00:00.000Z"},
+	})
+	m.addJobs("456-1", []*circle.Job{
+		{ID: "456-1-1", JobNumber: 1, Name: "build", Status: "success"},
+		{ID: "456-1-2", JobNumber: 2, Name: "test", Status: "failed"},
+		{ID: "456-1-3", Name: "approve", Type: "approval", Status: "success"},
+	})
+	m.artifactsMap[1] = []*circle.Artifact{
+		{Path: "dist/app.tar.gz", NodeIndex: 0, URL: "https://example.com/1/app.tar.gz"},
+		{Path: "dist/checksums.txt", NodeIndex: 0, URL: "https://example.com/1/checksums.txt"},
+	}
+	m.artifactsMap[2] = []*circle.Artifact{
+		{Path: "results.xml", NodeIndex: 0, URL: "https://example.com/2/0/results.xml"},
+		{Path: "results.xml", NodeIndex: 1, URL: "https://example.com/2/1/results.xml"},
+	}
+	m.contentsMap["https://example.com/1/app.tar.gz"] = "archive"
+	m.contentsMap["https://example.com/1/checksums.txt"] = "checksums"
+	m.contentsMap["https://example.com/2/0/results.xml"] = "<node0/>"
+	m.contentsMap["https://example.com/2/1/results.xml"] = "<node1/>"
+	return m
+}
+
+func Test_DownloadArtifacts(t *testing.T) {
+	baseOpts := DownloadArtifactsOptions{
+		ProjectType:    "github",
+		Org:            "influxdata",
+		Project:        "testproject",
+		PipelineNumber: 123,
+	}
+
+	for _, test := range []struct {
+		name          string
+		opts          func(opts *DownloadArtifactsOptions)
+		expectedFiles map[string]string
+		expectedError bool
+	}{
+		{
+			name: "all artifacts",
+			opts: func(opts *DownloadArtifactsOptions) {},
+			expectedFiles: map[string]string{
+				"build/0/dist/app.tar.gz":    "archive",
+				"build/0/dist/checksums.txt": "checksums",
+				"test/0/results.xml":         "<node0/>",
+				"test/1/results.xml":         "<node1/>",
+			},
+		},
+		{
+			name: "file name glob",
+			opts: func(opts *DownloadArtifactsOptions) { opts.IncludePaths = []string{"*.tar.gz", "*.xml"} },
+			expectedFiles: map[string]string{
+				"build/0/dist/app.tar.gz": "archive",
+				"test/0/results.xml":      "<node0/>",
+				"test/1/results.xml":      "<node1/>",
+			},
+		},
+		{
+			name: "path glob and job filter",
+			opts: func(opts *DownloadArtifactsOptions) {
+				opts.IncludePaths = []string{"dist/*"}
+				opts.ExcludePaths = []string{"*.txt"}
+				opts.IncludeJobs = []string{"build"}
+			},
+			expectedFiles: map[string]string{
+				"build/0/dist/app.tar.gz": "archive",
+			},
+		},
+		{
+			name:          "dry run",
+			opts:          func(opts *DownloadArtifactsOptions) { opts.DryRun = true },
+			expectedFiles: map[string]string{},
+		},
+		{
+			name:          "too large",
+			opts:          func(opts *DownloadArtifactsOptions) { opts.MaxSize = 7 },
+			expectedError: true,
+		},
+	} {
+		t.Run(test.name, func(tt *testing.T) {
+			opts := baseOpts
+			opts.TargetDir = tt.TempDir()
+			test.opts(&opts)
+
+			result, err := DownloadArtifacts(context.Background(), zap.NewNop(), newMockCircleClientWithArtifacts(), opts)
+			if test.expectedError {
+				if err == nil {
+					tt.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				tt.Fatalf("unexpected error: %v", err)
+			}
+
+			files := map[string]string{}
+			err = filepath.WalkDir(opts.TargetDir, func(file string, entry os.DirEntry, err error) error {
+				if err != nil || entry.IsDir() {
+					return err
+				}
+				contents, err := os.ReadFile(file)
+				if err != nil {
+					return err
+				}
+				relative, _ := filepath.Rel(opts.TargetDir, file)
+				files[filepath.ToSlash(relative)] = string(contents)
+				return nil
+			})
+			if err != nil {
+				tt.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(test.expectedFiles, files) {
+				tt.Errorf("invalid files; want %v, got %v", test.expectedFiles, files)
+			}
+			if opts.DryRun {
+				if want, got := 4, len(result); want != got {
+					tt.Errorf("invalid number of listed artifacts; want %v, got %v", want, got)
+				}
+			} else if want, got := len(test.expectedFiles), len(result); want != got {
+				tt.Errorf("invalid number of artifacts; want %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func Test_DownloadArtifacts_pathTraversal(t *testing.T) {
+	m := newMockCircleClientWithArtifacts()
+	m.artifactsMap[1] = append(m.artifactsMap[1], &circle.Artifact{Path: "../../../etc/passwd", URL: "https://example.com/1/passwd"})
+	m.contentsMap["https://example.com/1/passwd"] = "root"
+
+	dir := t.TempDir()
+	_, err := DownloadArtifacts(context.Background(), zap.NewNop(), m, DownloadArtifactsOptions{
+		ProjectType:    "github",
+		Org:            "influxdata",
+		Project:        "testproject",
+		PipelineNumber: 123,
+		TargetDir:      filepath.Join(dir, "artifacts"),
+	})
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "artifacts")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be downloaded")
+	}
+}
+
+func Test_DownloadArtifacts_runningWorkflow(t *testing.T) {
+	m := newMockCircleClientWithArtifacts()
+	// a job in the same workflow downloading artifacts of earlier jobs, while the workflow is still running
+	m.workflowsMap["456"][0].Status = "running"
+	m.addJobs("456-1", append(m.jobsMap["456-1"], &circle.Job{ID: "456-1-4", JobNumber: 3, Name: "publish", Status: "running"}))
+	m.artifactsMap[3] = []*circle.Artifact{
+		{Path: "partial.txt", NodeIndex: 0, URL: "https://example.com/3/partial.txt"},
+	}
+
+	dir := t.TempDir()
+	result, err := DownloadArtifacts(context.Background(), zap.NewNop(), m, DownloadArtifactsOptions{
+		ProjectType:    "github",
+		Org:            "influxdata",
+		Project:        "testproject",
+		PipelineNumber: 123,
+		IncludeJobs:    []string{"build", "publish"},
+		TargetDir:      dir,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// artifacts of finished jobs are downloaded, but not ones of jobs that are still running
+	var files []string
+	for _, artifact := range result {
+		files = append(files, filepath.ToSlash(artifact.File))
+	}
+	expected := []string{
+		filepath.ToSlash(filepath.Join(dir, "build", "0", "dist", "app.tar.gz")),
+		filepath.ToSlash(filepath.Join(dir, "build", "0", "dist", "checksums.txt")),
+	}
+	if !reflect.DeepEqual(expected, files) {
+		t.Errorf("invalid files; want %v, got %v", expected, files)
+	}
+}
@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+)
+
+// CancelWorkflowsOptions allows passing options for canceling one or more workflows.
+type CancelWorkflowsOptions struct {
+	ProjectType      string
+	Org              string
+	Project          string
+	PipelineNumber   int
+	WorkflowNames    []string
+	IncludeWorkflows []string
+	ExcludeWorkflows []string
+	DryRun           bool
+}
+
+// CancelWorkflows cancels all workflows matching criteria that have not finished yet, returning workflows that were canceled.
+func CancelWorkflows(ctx context.Context, logger *zap.Logger, client circle.Client, opts CancelWorkflowsOptions) ([]*circle.Workflow, error) {
+	workflowFilter, err := newWorkflowFilter(opts.WorkflowNames, opts.IncludeWorkflows, opts.ExcludeWorkflows)
+	if err != nil {
+		return nil, err
+	}
+
+	pipelineID, err := client.GetPipelineID(ctx, opts.ProjectType, opts.Org, opts.Project, opts.PipelineNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	workflows, err := getLatestWorkflows(ctx, client, pipelineID, filterWorkflowByName(workflowFilter, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	return cancelRunningWorkflows(ctx, logger, client, workflows, opts.DryRun)
+}
+
+// cancelRunningWorkflows cancels workflows that have not finished yet, logging each cancellation.
+func cancelRunningWorkflows(ctx context.Context, logger *zap.Logger, client circle.Client, workflows []*circle.Workflow, dryRun bool) ([]*circle.Workflow, error) {
+	sugar := logger.Sugar()
+
+	canceled := []*circle.Workflow{}
+	for _, workflow := range workflows {
+		// workflows that have already finished cannot be canceled
+		if circle.WorkflowFinished(workflow) {
+			continue
+		}
+
+		if dryRun {
+			sugar.Infof("would cancel workflow %s (id: %s, status: %s)", workflow.Name, workflow.ID, workflow.Status)
+		} else {
+			sugar.Warnf("canceling workflow %s (id: %s, status: %s)", workflow.Name, workflow.ID, workflow.Status)
+			if err := client.CancelWorkflow(ctx, workflow.ID); err != nil {
+				return canceled, err
+			}
+		}
+
+		canceled = append(canceled, workflow)
+	}
+
+	return canceled, nil
+}
+
+// CancelOlderPipelinesOptions allows passing options for canceling workflows of older pipelines on the same branch.
+type CancelOlderPipelinesOptions struct {
+	ProjectType string
+	Org         string
+	Project     string
+	// PipelineNumber is the current pipeline, only workflows of pipelines with lower numbers are canceled.
+	PipelineNumber int
+	// Branch to cancel pipelines on, branch of the current pipeline if not set.
+	Branch string
+	// MaxPipelines limits how many of the most recent pipelines on the branch are checked, if set.
+	MaxPipelines     int
+	WorkflowNames    []string
+	IncludeWorkflows []string
+	ExcludeWorkflows []string
+	DryRun           bool
+}
+
+// CancelOlderPipelines cancels workflows matching criteria that have not finished yet in pipelines on the same branch
+// that are older than the current pipeline, returning workflows that were canceled.
+func CancelOlderPipelines(ctx context.Context, logger *zap.Logger, client circle.Client, opts CancelOlderPipelinesOptions) ([]*circle.Workflow, error) {
+	sugar := logger.Sugar()
+
+	workflowFilter, err := newWorkflowFilter(opts.WorkflowNames, opts.IncludeWorkflows, opts.ExcludeWorkflows)
+	if err != nil {
+		return nil, err
+	}
+
+	pipelines, branch, err := getOlderPipelines(ctx, client, opts.ProjectType, opts.Org, opts.Project, opts.PipelineNumber, opts.Branch, opts.MaxPipelines)
+	if err != nil {
+		return nil, err
+	}
+
+	canceled := []*circle.Workflow{}
+	for _, pipeline := range pipelines {
+		workflows, err := getLatestWorkflows(ctx, client, pipeline.ID, filterWorkflowByName(workflowFilter, nil))
+		if err != nil {
+			return canceled, err
+		}
+
+		if len(workflows) > 0 {
+			sugar.Infof("checking workflows of pipeline %d on branch %s", pipeline.Number, branch)
+		}
+		pipelineCanceled, err := cancelRunningWorkflows(ctx, logger, client, workflows, opts.DryRun)
+		canceled = append(canceled, pipelineCanceled...)
+		if err != nil {
+			return canceled, err
+		}
+	}
+
+	return canceled, nil
+}
+
+// getOlderPipelines returns pipelines on a branch that are older than the specified pipeline, newest first, along with the branch.
+// If branch is empty, the branch of the specified pipeline is used. If maxPipelines is set, only that many most recent
+// pipelines on the branch are checked.
+func getOlderPipelines(
+	ctx context.Context,
+	client circle.Client,
+	projectType, org, project string,
+	pipelineNumber int,
+	branch string,
+	maxPipelines int,
+) ([]*circle.Pipeline, string, error) {
+	if branch == "" {
+		current, err := client.GetPipeline(ctx, projectType, org, project, pipelineNumber)
+		if err != nil {
+			return nil, "", err
+		}
+		if current.VCS.Branch == "" {
+			return nil, "", fmt.Errorf("pipeline %d is not for a branch, branch must be specified", pipelineNumber)
+		}
+		branch = current.VCS.Branch
+	}
+
+	pipelines, err := client.ListPipelines(ctx, projectType, org, project, circle.ListPipelinesOptions{
+		Branch: branch,
+		Limit:  maxPipelines,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	older := []*circle.Pipeline{}
+	for _, pipeline := range pipelines {
+		if pipeline.Number < pipelineNumber {
+			older = append(older, pipeline)
+		}
+	}
+
+	return older, branch, nil
+}
@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+)
+
+func Test_CancelWorkflows(t *testing.T) {
+	opts := CancelWorkflowsOptions{
+		ProjectType:    "github",
+		Org:            "influxdata",
+		Project:        "testproject",
+		PipelineNumber: 123,
+	}
+
+	// dry run should not cancel anything
+	m := newMockCircleClientWithData("running", "success", "running", "success")
+	opts.DryRun = true
+	canceled, err := CancelWorkflows(context.Background(), zap.NewNop(), m, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := 1, len(canceled); want != got {
+		t.Fatalf("invalid number of canceled workflows; want %v, got %v", want, got)
+	}
+	if want, got := 0, len(m.canceled); want != got {
+		t.Errorf("invalid number of workflows canceled in dry run; want %v, got %v", want, got)
+	}
+
+	// only the latest, still running, workflow should be canceled
+	opts.DryRun = false
+	canceled, err = CancelWorkflows(context.Background(), zap.NewNop(), m, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := 1, len(canceled); want != got {
+		t.Fatalf("invalid number of canceled workflows; want %v, got %v", want, got)
+	}
+	if want, got := []string{"456-2"}, m.canceled; len(got) != 1 || want[0] != got[0] {
+		t.Errorf("invalid workflows canceled; want %v, got %v", want, got)
+	}
+}
+
+func Test_CancelOlderPipelines(t *testing.T) {
+	m := newMockCircleClient("github", "influxdata", "testproject")
+	m.addPipelineForBranch(10, "p10", "main")
+	m.addPipelineForBranch(11, "p11", "feature")
+	m.addPipelineForBranch(12, "p12", "main")
+	m.addPipelineForBranch(13, "p13", "main")
+	m.addPipelineForBranch(14, "p14", "main")
+	for _, id := range []string{"p10", "p11", "p12", "p13", "p14"} {
+		m.addWorkflows(id, []*circle.Workflow{
+			{ID: id + "-build", Name: "build", Status: "running", CreatedAt: "2021-01-01T00:00:00.000Z"},
+			{ID: id + "-deploy", Name: "deploy", Status: "running", CreatedAt: "2021-01-01T00:00:00.000Z"},
+		})
+	}
+	m.workflowsMap["p10"][0].Status = "success"
+
+	opts := CancelOlderPipelinesOptions{
+		ProjectType:    "github",
+		Org:            "influxdata",
+		Project:        "testproject",
+		PipelineNumber: 13,
+		WorkflowNames:  []string{"build"},
+		DryRun:         true,
+	}
+
+	// dry run should not cancel anything, branch defaults to the one of the current pipeline
+	canceled, err := CancelOlderPipelines(context.Background(), zap.NewNop(), m, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := 1, len(canceled); want != got {
+		t.Fatalf("invalid number of canceled workflows; want %v, got %v", want, got)
+	}
+	if want, got := 0, len(m.canceled); want != got {
+		t.Errorf("invalid number of workflows canceled in dry run; want %v, got %v", want, got)
+	}
+
+	// only running workflows of older pipelines on the same branch should be canceled
+	opts.DryRun = false
+	opts.WorkflowNames = nil
+	if _, err := CancelOlderPipelines(context.Background(), zap.NewNop(), m, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := []string{"p12-build", "p12-deploy", "p10-deploy"}, m.canceled; !reflect.DeepEqual(want, got) {
+		t.Errorf("invalid workflows canceled; want %v, got %v", want, got)
+	}
+
+	// number of checked pipelines can be limited
+	m.canceled = nil
+	opts.Branch = "main"
+	opts.MaxPipelines = 2
+	if _, err := CancelOlderPipelines(context.Background(), zap.NewNop(), m, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := 0, len(m.canceled); want != got {
+		t.Errorf("invalid number of canceled workflows; want %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultConcurrency is the number of CircleCI API requests, such as retrieving jobs of workflows, details and output
+// of jobs or downloading artifacts, that are made in parallel if not specified.
+const DefaultConcurrency = 8
+
+// forEachConcurrently calls fn for each index from 0 to n-1, with at most concurrency calls running at the same time
+// (DefaultConcurrency if not set). Callers store results by index, so that their order does not depend on the order
+// calls finished in. Once a call fails, the context passed to other calls is canceled, no further calls are started
+// and the first error is returned.
+func forEachConcurrently(ctx context.Context, concurrency int, n int, fn func(ctx context.Context, i int) error) error {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(concurrency)
+	for i := 0; i < n; i++ {
+		// do not start further calls once a call has failed
+		if groupCtx.Err() != nil {
+			break
+		}
+		group.Go(func() error {
+			return fn(groupCtx, i)
+		})
+	}
+	return group.Wait()
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_forEachConcurrently(t *testing.T) {
+	var running, maxRunning int32
+	results := make([]int, 20)
+	err := forEachConcurrently(context.Background(), 3, len(results), func(ctx context.Context, i int) error {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			previous := atomic.LoadInt32(&maxRunning)
+			if current <= previous || atomic.CompareAndSwapInt32(&maxRunning, previous, current) {
+				break
+			}
+		}
+		// finish calls in a different order than they were started in
+		time.Sleep(time.Duration(len(results)-i) * time.Millisecond)
+		results[i] = i * i
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if maxRunning > 3 {
+		t.Errorf("too many calls running at the same time; want at most 3, got %v", maxRunning)
+	}
+	for i, result := range results {
+		if want, got := i*i, result; want != got {
+			t.Errorf("invalid result %d; want %v, got %v", i, want, got)
+		}
+	}
+
+	// the first error cancels other calls and further calls are not started
+	expectedErr := errors.New("failed")
+	var started int32
+	err = forEachConcurrently(context.Background(), 2, 100, func(ctx context.Context, i int) error {
+		atomic.AddInt32(&started, 1)
+		if i == 0 {
+			return expectedErr
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("invalid error; want %v, got %v", expectedErr, err)
+	}
+	if started >= 100 {
+		t.Errorf("expected calls not to be started after an error, got %v calls", started)
+	}
+}
@@ -0,0 +1,273 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+)
+
+// ANSI escape sequences used for rendering the dashboard
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiReverse = "\x1b[7m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiGray    = "\x1b[90m"
+)
+
+// frames of the spinner shown for running workflows and jobs
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// dashboardRow is a single workflow or job shown in the dashboard ; job is nil for workflows.
+type dashboardRow struct {
+	workflow *circle.Workflow
+	job      *circle.Job
+}
+
+func (r *dashboardRow) key() string {
+	if r.job == nil {
+		return r.workflow.Name
+	}
+	return r.workflow.Name + "/" + r.job.Name
+}
+
+func (r *dashboardRow) failed() bool {
+	if r.job == nil {
+		// workflows that are still running but already have failed jobs are highlighted as well
+		return circle.WorkflowFailed(r.workflow) || r.workflow.Status == "failing"
+	}
+	return circle.JobFailed(r.job)
+}
+
+// Dashboard keeps state of a live terminal view of workflows and their jobs and renders it.
+// It is not safe for concurrent use.
+type Dashboard struct {
+	title    string
+	rows     []dashboardRow
+	selected int
+	frame    int
+	message  string
+
+	showLog   bool
+	logTitle  string
+	logLines  []string
+	logOffset int
+}
+
+// NewDashboard creates a new Dashboard with specified title.
+func NewDashboard(title string) *Dashboard {
+	return &Dashboard{title: title}
+}
+
+// Update replaces workflows and jobs shown in the dashboard, keeping the selected row if it still exists.
+func (d *Dashboard) Update(result *WorkflowsSummary) {
+	selectedKey := ""
+	if d.selected < len(d.rows) {
+		selectedKey = d.rows[d.selected].key()
+	}
+
+	workflows := append([]*WorkflowDetails{}, result.AllWorkflows...)
+	sort.SliceStable(workflows, func(a, b int) bool {
+		return workflows[a].Workflow.Name < workflows[b].Workflow.Name
+	})
+
+	d.rows = nil
+	for _, details := range workflows {
+		d.rows = append(d.rows, dashboardRow{workflow: details.Workflow})
+
+		var jobs []*circle.Job
+		jobs = append(jobs, details.SucceededJobs...)
+		jobs = append(jobs, details.FailedJobs...)
+		jobs = append(jobs, details.AllowedFailedJobs...)
+		jobs = append(jobs, details.PendingJobs...)
+		sort.SliceStable(jobs, func(a, b int) bool {
+			return jobs[a].Name < jobs[b].Name
+		})
+		for _, job := range jobs {
+			d.rows = append(d.rows, dashboardRow{workflow: details.Workflow, job: job})
+		}
+	}
+
+	d.selected = 0
+	for i := range d.rows {
+		if d.rows[i].key() == selectedKey {
+			d.selected = i
+		}
+	}
+}
+
+// SetMessage sets the message shown in the status line.
+func (d *Dashboard) SetMessage(format string, args ...interface{}) {
+	d.message = fmt.Sprintf(format, args...)
+}
+
+// Tick advances the spinner.
+func (d *Dashboard) Tick() {
+	d.frame++
+}
+
+// MoveSelection moves selection by delta rows, or scrolls the log if it is shown.
+func (d *Dashboard) MoveSelection(delta int) {
+	if d.showLog {
+		d.logOffset = clamp(d.logOffset+delta, 0, len(d.logLines)-1)
+		return
+	}
+	d.selected = clamp(d.selected+delta, 0, len(d.rows)-1)
+}
+
+// SelectNextFailure selects next failed workflow or job after the currently selected one, wrapping around.
+func (d *Dashboard) SelectNextFailure() bool {
+	for i := 1; i <= len(d.rows); i++ {
+		index := (d.selected + i) % len(d.rows)
+		if d.rows[index].failed() {
+			d.selected = index
+			return true
+		}
+	}
+	return false
+}
+
+// Selected returns the selected workflow and job, if a job is selected.
+func (d *Dashboard) Selected() (*circle.Workflow, *circle.Job) {
+	if d.selected >= len(d.rows) {
+		return nil, nil
+	}
+	return d.rows[d.selected].workflow, d.rows[d.selected].job
+}
+
+// ShowLog shows a log instead of workflows, scrolled to its end.
+func (d *Dashboard) ShowLog(title string, log string) {
+	d.showLog = true
+	d.logTitle = title
+	// carriage returns and tabs would break the layout of the terminal
+	log = strings.NewReplacer("\r\n", "\n", "\r", "\n", "\t", "    ").Replace(log)
+	d.logLines = strings.Split(strings.TrimRight(log, "\n"), "\n")
+	d.logOffset = len(d.logLines) - 1
+}
+
+// CloseLog returns back to showing workflows.
+func (d *Dashboard) CloseLog() {
+	d.showLog = false
+}
+
+// LogVisible returns whether a log is shown.
+func (d *Dashboard) LogVisible() bool {
+	return d.showLog
+}
+
+// Render returns lines to show on a terminal of specified size, using ANSI colors if color is true.
+func (d *Dashboard) Render(now time.Time, width int, height int, color bool) []string {
+	style := func(text string, codes ...string) string {
+		text = truncate(text, width)
+		if !color || len(codes) == 0 {
+			return text
+		}
+		return strings.Join(codes, "") + text + ansiReset
+	}
+
+	var lines []string
+	if d.showLog {
+		// show as many lines as fit, ending with the line at current offset
+		visible := max(height-2, 1)
+		end := d.logOffset + 1
+		start := max(end-visible, 0)
+		lines = append(lines, style(fmt.Sprintf("%s (lines %d-%d of %d)", d.logTitle, start+1, end, len(d.logLines)), ansiBold))
+		for _, line := range d.logLines[start:end] {
+			lines = append(lines, truncate(line, width))
+		}
+		lines = append(lines, style("↑/↓ scroll  esc/q back", ansiGray))
+		return lines
+	}
+
+	lines = append(lines, style(d.title, ansiBold))
+
+	// only show rows that fit, keeping the selected row visible
+	visible := max(height-2, 1)
+	start := 0
+	if d.selected >= visible {
+		start = d.selected - visible + 1
+	}
+	end := min(start+visible, len(d.rows))
+
+	for i := start; i < end; i++ {
+		row := &d.rows[i]
+		var symbol, colorCode, text string
+		if row.job == nil {
+			symbol, colorCode = d.statusSymbol(row.workflow.Status, circle.WorkflowFinished(row.workflow), row.failed())
+			finished := ""
+			if circle.WorkflowFinished(row.workflow) {
+				finished = row.workflow.StoppedAt
+			}
+			text = fmt.Sprintf("%s %s (%s%s)", symbol, row.workflow.Name, row.workflow.Status, describeElapsed(now, row.workflow.CreatedAt, finished))
+		} else {
+			symbol, colorCode = d.statusSymbol(row.job.Status, circle.JobFinished(row.job), row.failed())
+			if circle.JobAwaitingApproval(row.job) {
+				symbol, colorCode = "⏸", ansiYellow
+			}
+			text = fmt.Sprintf("    %s %s (%s%s)", symbol, row.job.Name, row.job.Status, describeElapsed(now, row.job.StartedAt, row.job.StoppedAt))
+		}
+
+		codes := []string{}
+		if colorCode != "" {
+			codes = append(codes, colorCode)
+		}
+		if row.job == nil {
+			codes = append(codes, ansiBold)
+		}
+		if i == d.selected {
+			codes = append(codes, ansiReverse)
+			if !color {
+				text = ">" + text
+			}
+		}
+		lines = append(lines, style(text, codes...))
+	}
+
+	footer := "↑/↓ select  f next failure  enter/l open log  q quit"
+	if d.message != "" {
+		footer = d.message + "  |  " + footer
+	}
+	lines = append(lines, style(footer, ansiGray))
+
+	return lines
+}
+
+// statusSymbol returns symbol and color for a workflow or job status.
+func (d *Dashboard) statusSymbol(status string, finished bool, failed bool) (string, string) {
+	switch {
+	case failed:
+		return "✗", ansiRed
+	case finished:
+		return "✓", ansiGreen
+	case status == "running":
+		return spinnerFrames[d.frame%len(spinnerFrames)], ansiYellow
+	default:
+		return "·", ansiGray
+	}
+}
+
+// describeElapsed returns time elapsed since start until stop, or now if stop is not set, for including in a status.
+func describeElapsed(now time.Time, start string, stop string) string {
+	if duration := formatDuration(now, start, stop); duration != "" {
+		return ", " + duration
+	}
+	return ""
+}
+
+// truncate shortens text to at most width characters.
+func truncate(text string, width int) string {
+	runes := []rune(text)
+	if width <= 0 || len(runes) <= width {
+		return text
+	}
+	return string(runes[:width])
+}
+
+func clamp(value int, low int, high int) int {
+	return max(low, min(value, high))
+}
@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+)
+
+func newDashboardTestSummary() *WorkflowsSummary {
+	workflow := &WorkflowDetails{
+		Workflow: &circle.Workflow{ID: "1", Name: "build", Status: "failing", CreatedAt: "2021-01-01T00:00:00Z"},
+		SucceededJobs: []*circle.Job{
+			{Name: "lint", Status: "success", StartedAt: "2021-01-01T00:00:00Z", StoppedAt: "2021-01-01T00:00:30Z"},
+		},
+		FailedJobs: []*circle.Job{
+			{Name: "test", Status: "failed", StartedAt: "2021-01-01T00:00:00Z", StoppedAt: "2021-01-01T00:01:00Z"},
+		},
+		PendingJobs: []*circle.Job{
+			{Name: "deploy", Status: "blocked"},
+		},
+	}
+	return &WorkflowsSummary{
+		AllWorkflows:     []*WorkflowDetails{workflow},
+		PendingWorkflows: []*WorkflowDetails{workflow},
+	}
+}
+
+func Test_Dashboard_Render(t *testing.T) {
+	d := NewDashboard("influxdata/testproject pipeline 123")
+	d.Update(newDashboardTestSummary())
+
+	now := time.Date(2021, 1, 1, 0, 2, 0, 0, time.UTC)
+	want := []string{
+		"influxdata/testproject pipeline 123",
+		">✗ build (failing, 2m0s)",
+		"    · deploy (blocked)",
+		"    ✓ lint (success, 30s)",
+		"    ✗ test (failed, 1m0s)",
+		"↑/↓ select  f next failure  enter/l open log  q quit",
+	}
+	if got := d.Render(now, 80, 24, false); !reflect.DeepEqual(want, got) {
+		t.Errorf("invalid output; want %q, got %q", want, got)
+	}
+
+	// only rows that fit should be shown, keeping selected row visible
+	d.MoveSelection(3)
+	want = []string{
+		"influxdata/testproject pipeline 123",
+		"    ✓ lint (success, 30s)",
+		">    ✗ test (failed, 1m0s)",
+		"↑/↓ select  f next failure  enter/l open", // truncated to width
+	}
+	if got := d.Render(now, 40, 4, false); !reflect.DeepEqual(want, got) {
+		t.Errorf("invalid output; want %q, got %q", want, got)
+	}
+}
+
+func Test_Dashboard_selection(t *testing.T) {
+	d := NewDashboard("test")
+	d.Update(newDashboardTestSummary())
+
+	if !d.SelectNextFailure() {
+		t.Fatalf("expected a failure to be selected")
+	}
+	if _, job := d.Selected(); job == nil || job.Name != "test" {
+		t.Errorf("expected job test to be selected, got %v", job)
+	}
+
+	// selection should be kept when the dashboard is updated
+	d.Update(newDashboardTestSummary())
+	if _, job := d.Selected(); job == nil || job.Name != "test" {
+		t.Errorf("expected job test to still be selected, got %v", job)
+	}
+
+	// moving selection should stop at the first and last rows
+	d.MoveSelection(10)
+	if _, job := d.Selected(); job == nil || job.Name != "test" {
+		t.Errorf("expected job test to be selected, got %v", job)
+	}
+	d.MoveSelection(-10)
+	if workflow, job := d.Selected(); job != nil || workflow.Name != "build" {
+		t.Errorf("expected workflow build to be selected, got %v", job)
+	}
+}
+
+func Test_Dashboard_ShowLog(t *testing.T) {
+	d := NewDashboard("test")
+	d.ShowLog("job test", "line 1\r\nline 2\n\tline 3\n")
+	if !d.LogVisible() {
+		t.Fatalf("expected log to be visible")
+	}
+
+	want := []string{
+		"job test (lines 2-3 of 3)",
+		"line 2",
+		"    line 3",
+		"↑/↓ scroll  esc/q back",
+	}
+	if got := d.Render(time.Now(), 80, 4, false); !reflect.DeepEqual(want, got) {
+		t.Errorf("invalid output; want %q, got %q", want, got)
+	}
+
+	d.CloseLog()
+	if d.LogVisible() {
+		t.Errorf("expected log to be closed")
+	}
+}
@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+)
+
+// downstreamJobIDs returns IDs of jobs that depend, directly or transitively, on any of the specified job IDs.
+func downstreamJobIDs(jobs []*circle.Job, ids map[string]bool) map[string]bool {
+	result := map[string]bool{}
+
+	// repeat until no more jobs are added, as jobs are not guaranteed to be sorted in dependency order
+	for changed := true; changed; {
+		changed = false
+		for _, job := range jobs {
+			if result[job.ID] {
+				continue
+			}
+			for _, dependency := range job.Dependencies {
+				if ids[dependency] || result[dependency] {
+					result[job.ID] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// upstreamJobIDs returns IDs of jobs that specified job depends on, directly or transitively.
+func upstreamJobIDs(jobs []*circle.Job, job *circle.Job) map[string]bool {
+	jobsByID := map[string]*circle.Job{}
+	for _, j := range jobs {
+		jobsByID[j.ID] = j
+	}
+
+	result := map[string]bool{}
+	queue := append([]string{}, job.Dependencies...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if result[id] {
+			continue
+		}
+		result[id] = true
+		if dependency, ok := jobsByID[id]; ok {
+			queue = append(queue, dependency.Dependencies...)
+		}
+	}
+
+	return result
+}
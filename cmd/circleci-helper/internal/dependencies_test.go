@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+)
+
+// jobs forming a DAG: lint, build -> test -> finalize ; docs has no dependencies
+func newDependencyTestJobs() []*circle.Job {
+	return []*circle.Job{
+		{ID: "1", Name: "lint", Status: "success"},
+		{ID: "2", Name: "build", Status: "success"},
+		{ID: "3", Name: "test", Status: "running", Dependencies: []string{"2"}},
+		{ID: "4", Name: "finalize", Status: "running", Dependencies: []string{"1", "3"}},
+		{ID: "5", Name: "docs", Status: "running"},
+	}
+}
+
+func Test_upstreamJobIDs(t *testing.T) {
+	jobs := newDependencyTestJobs()
+	result := upstreamJobIDs(jobs, jobs[3])
+	if want, got := 3, len(result); want != got {
+		t.Errorf("invalid number of upstream jobs; want %v, got %v", want, got)
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		if !result[id] {
+			t.Errorf("job %s should be upstream of finalize", id)
+		}
+	}
+}
+
+func Test_downstreamJobIDs(t *testing.T) {
+	jobs := newDependencyTestJobs()
+	result := downstreamJobIDs(jobs, map[string]bool{"2": true})
+	if want, got := 2, len(result); want != got {
+		t.Errorf("invalid number of downstream jobs; want %v, got %v", want, got)
+	}
+	for _, id := range []string{"3", "4"} {
+		if !result[id] {
+			t.Errorf("job %s should be downstream of build", id)
+		}
+	}
+}
+
+func Test_checkWorkflowsStatus_upstreamOf(t *testing.T) {
+	m := newMockCircleClient("github", "influxdata", "testproject")
+	m.addWorkflows("456", []*circle.Workflow{
+		{ID: "456-1", Name: "build", Status: "running", CreatedAt: "2021-01-01T00:00:00.000Z"},
+		{ID: "456-2", Name: "nightly", Status: "failed", CreatedAt: "2021-01-01T00:00:00.000Z"},
+	})
+	m.addJobs("456-1", newDependencyTestJobs())
+	m.addJobs("456-2", []*circle.Job{
+		{ID: "6", Name: "benchmark", Status: "failed"},
+	})
+
+	result, err := checkWorkflowsStatus(context.Background(), m, "456", checkWorkflowStatusOpts{
+		pendingJobDetails: true,
+		upstreamOf:        "finalize",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// workflows that do not contain finalize are ignored
+	if want, got := 1, len(result.AllWorkflows); want != got {
+		t.Fatalf("invalid number of workflows; want %v, got %v", want, got)
+	}
+	if result.Failed {
+		t.Errorf("expected result not to be failed by a workflow that does not contain finalize")
+	}
+
+	// only test is still pending, as docs and finalize itself are not upstream jobs of finalize
+	if want, got := 1, len(result.PendingWorkflows); want != got {
+		t.Fatalf("invalid number of pending workflows; want %v, got %v", want, got)
+	}
+	details := result.PendingWorkflows[0]
+	if want, got := 1, len(details.PendingJobs); want != got {
+		t.Fatalf("invalid number of pending jobs; want %v, got %v", want, got)
+	}
+	if want, got := "test", details.PendingJobs[0].Name; want != got {
+		t.Errorf("invalid pending job; want %v, got %v", want, got)
+	}
+	if want, got := 2, len(details.SucceededJobs); want != got {
+		t.Errorf("invalid number of succeeded jobs; want %v, got %v", want, got)
+	}
+}
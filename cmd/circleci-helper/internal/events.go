@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"time"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+)
+
+// Event is an event emitted by WaitForJobs, one of WorkflowAppeared, JobStatusChanged, WorkflowFinished,
+// PollCompleted, PollError or WaitFinished.
+type Event interface {
+	isEvent()
+}
+
+// WorkflowAppeared is emitted when a workflow matching criteria is seen for the first time.
+type WorkflowAppeared struct {
+	Workflow *circle.Workflow
+}
+
+// JobStatusChanged is emitted when a job is seen for the first time or its status has changed since the previous check.
+type JobStatusChanged struct {
+	Workflow *circle.Workflow
+	Job      *circle.Job
+	// PreviousStatus is the status of the job as of the previous check, empty if the job is seen for the first time.
+	PreviousStatus string
+	// AllowedToFail is whether the job has failed, but is allowed to fail.
+	AllowedToFail bool
+	// MatrixJob is the matrix job that the job is a variant of, if grouping of matrix jobs was requested.
+	MatrixJob *MatrixJob
+}
+
+// WorkflowFinished is emitted when a workflow has finished, successfully or not.
+type WorkflowFinished struct {
+	Details *WorkflowDetails
+}
+
+// PollCompleted is emitted after each check of workflows, after events for any changes.
+type PollCompleted struct {
+	Result *WorkflowsSummary
+	// NextPoll is how long WaitForJobs waits before the next check, zero if it does not check again.
+	NextPoll time.Duration
+}
+
+// PollError is emitted when checking workflows has failed.
+type PollError struct {
+	Err error
+}
+
+// WaitFinished is emitted once WaitForJobs returns, with its result or error.
+type WaitFinished struct {
+	Result *WorkflowsSummary
+	Err    error
+}
+
+func (WorkflowAppeared) isEvent() {}
+func (JobStatusChanged) isEvent() {}
+func (WorkflowFinished) isEvent() {}
+func (PollCompleted) isEvent()    {}
+func (PollError) isEvent()        {}
+func (WaitFinished) isEvent()     {}
+
+// eventTracker keeps state of workflows and jobs between checks to generate events for changes.
+type eventTracker struct {
+	// statuses of workflows by workflow ID and of jobs by workflow ID and job name, as of last check
+	workflowStatus map[string]string
+	jobStatus      map[string]string
+}
+
+func newEventTracker() *eventTracker {
+	return &eventTracker{
+		workflowStatus: map[string]string{},
+		jobStatus:      map[string]string{},
+	}
+}
+
+// changes returns events for workflows and jobs that appeared or changed their status since the previous check.
+func (t *eventTracker) changes(result *WorkflowsSummary) []Event {
+	var events []Event
+
+	for _, details := range result.AllWorkflows {
+		workflow := details.Workflow
+		previous, seen := t.workflowStatus[workflow.ID]
+		t.workflowStatus[workflow.ID] = workflow.Status
+		if !seen {
+			events = append(events, WorkflowAppeared{Workflow: workflow})
+		}
+
+		matrixJobs := map[string]*MatrixJob{}
+		for _, matrixJob := range details.MatrixJobs {
+			for _, name := range matrixJob.Jobs {
+				matrixJobs[name] = matrixJob
+			}
+		}
+
+		addJobs := func(jobs []*circle.Job, allowedToFail bool) {
+			for _, job := range jobs {
+				key := workflow.ID + "/" + job.Name
+				previous, seen := t.jobStatus[key]
+				if seen && previous == job.Status {
+					continue
+				}
+				t.jobStatus[key] = job.Status
+				events = append(events, JobStatusChanged{
+					Workflow:       workflow,
+					Job:            job,
+					PreviousStatus: previous,
+					AllowedToFail:  allowedToFail,
+					MatrixJob:      matrixJobs[job.Name],
+				})
+			}
+		}
+		addJobs(details.SucceededJobs, false)
+		addJobs(details.PendingJobs, false)
+		addJobs(details.FailedJobs, false)
+		addJobs(details.AllowedFailedJobs, true)
+
+		if circle.WorkflowFinished(workflow) && (!seen || previous != workflow.Status) {
+			events = append(events, WorkflowFinished{Details: details})
+		}
+	}
+
+	return events
+}
+
+// emitEvents returns a callback that passes events to all of subscribers that are not nil.
+func emitEvents(subscribers ...func(event Event)) func(event Event) {
+	return func(event Event) {
+		for _, subscriber := range subscribers {
+			if subscriber != nil {
+				subscriber(event)
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func Test_WaitForJobs_events(t *testing.T) {
+	m := newMockCircleClientWithApproval()
+
+	var events []Event
+	result, err := WaitForJobs(context.Background(), zap.NewNop(), m, WaitForJobsOptions{
+		ProjectType:    "github",
+		Org:            "influxdata",
+		Project:        "testproject",
+		PipelineNumber: 123,
+		OnHold:         OnHoldApprove,
+		WaitDuration:   NewWaitForJobsDuration(time.Millisecond),
+		OnEvent:        func(event Event) { events = append(events, event) },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(events) == 0 {
+		t.Fatalf("expected events to be emitted")
+	}
+	if appeared, ok := events[0].(WorkflowAppeared); !ok || appeared.Workflow.Name != "deploy" {
+		t.Errorf("expected first event to be WorkflowAppeared for deploy, got %#v", events[0])
+	}
+	if finished, ok := events[len(events)-1].(WaitFinished); !ok || finished.Result != result || finished.Err != nil {
+		t.Errorf("expected last event to be WaitFinished with the result, got %#v", events[len(events)-1])
+	}
+
+	changed := map[string]int{}
+	polls := 0
+	for _, event := range events {
+		switch e := event.(type) {
+		case JobStatusChanged:
+			changed[e.Job.Name]++
+		case PollCompleted:
+			polls++
+		}
+	}
+	for _, name := range []string{"build", "hold", "deploy", "notify"} {
+		if changed[name] == 0 {
+			t.Errorf("expected JobStatusChanged event for job %s", name)
+		}
+	}
+	// job that has not changed its status should only be reported once
+	if want, got := 1, changed["build"]; want != got {
+		t.Errorf("invalid number of JobStatusChanged events for job build; want %v, got %v", want, got)
+	}
+	if polls == 0 {
+		t.Errorf("expected PollCompleted events")
+	}
+}
+
+func Test_WaitForJobs_eventsOnError(t *testing.T) {
+	m := newMockCircleClientWithApproval()
+
+	var events []Event
+	_, err := WaitForJobs(context.Background(), zap.NewNop(), m, WaitForJobsOptions{
+		ProjectType:    "github",
+		Org:            "influxdata",
+		Project:        "testproject",
+		PipelineNumber: 123,
+		Where:          "job.name == ",
+		OnEvent:        func(event Event) { events = append(events, event) },
+	})
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	if want, got := 1, len(events); want != got {
+		t.Fatalf("invalid number of events; want %v, got %v", want, got)
+	}
+	if finished, ok := events[0].(WaitFinished); !ok || finished.Err != err {
+		t.Errorf("expected WaitFinished with the error, got %#v", events[0])
+	}
+}
+
+// failingApprovalClient fails to approve jobs.
+type failingApprovalClient struct {
+	*mockCircleClient
+}
+
+func (c *failingApprovalClient) ApproveJob(ctx context.Context, workflowID string, approvalRequestID string) error {
+	return fmt.Errorf("approval failed")
+}
+
+func Test_WaitForJobs_eventsOnApprovalError(t *testing.T) {
+	m := &failingApprovalClient{newMockCircleClientWithApproval()}
+
+	var events []Event
+	_, err := WaitForJobs(context.Background(), zap.NewNop(), m, WaitForJobsOptions{
+		ProjectType:    "github",
+		Org:            "influxdata",
+		Project:        "testproject",
+		PipelineNumber: 123,
+		OnHold:         OnHoldApprove,
+		WaitDuration:   NewWaitForJobsDuration(time.Millisecond),
+		OnEvent:        func(event Event) { events = append(events, event) },
+	})
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	var pollErrors []PollError
+	for _, event := range events {
+		if e, ok := event.(PollError); ok {
+			pollErrors = append(pollErrors, e)
+		}
+	}
+	if want, got := 1, len(pollErrors); want != got {
+		t.Fatalf("invalid number of PollError events; want %v, got %v", want, got)
+	}
+	if pollErrors[0].Err != err {
+		t.Errorf("invalid error in PollError; want %v, got %v", err, pollErrors[0].Err)
+	}
+}
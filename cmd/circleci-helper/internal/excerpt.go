@@ -0,0 +1,249 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DefaultExcerptTailLines is the number of last lines of output always kept in an excerpt.
+	DefaultExcerptTailLines = 50
+	// DefaultExcerptContextLines is the number of lines kept before and after interesting lines.
+	DefaultExcerptContextLines = 5
+	// DefaultExcerptMaxLines is the maximum number of lines kept around interesting lines, in addition to the last lines.
+	DefaultExcerptMaxLines = 500
+)
+
+// ansiEscapeRegexp matches ANSI escape sequences, such as colors and cursor movement, and OSC sequences such as hyperlinks.
+var ansiEscapeRegexp = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// StripANSI removes ANSI escape sequences from text.
+func StripANSI(text string) string {
+	return ansiEscapeRegexp.ReplaceAllString(text, "")
+}
+
+// excerptDetector matches lines of output that are interesting, such as test failures or compiler errors.
+type excerptDetector struct {
+	name    string
+	pattern *regexp.Regexp
+	// after is the number of lines following a matching line that are also kept, such as stack traces, in addition to context lines
+	after int
+}
+
+// builtinExcerptDetectors detect common failures in output of Go, compilers, npm and pytest.
+var builtinExcerptDetectors = []*excerptDetector{
+	{name: "go test", pattern: regexp.MustCompile(`^\s*--- FAIL: |^FAIL\s|^\s+\S+_test\.go:\d+: `)},
+	{name: "go panic", pattern: regexp.MustCompile(`^panic: |^fatal error: `), after: 40},
+	{name: "go build", pattern: regexp.MustCompile(`^\S+\.go:\d+(:\d+)?: `)},
+	{name: "compiler", pattern: regexp.MustCompile(`^\S+:\d+:\d+: (fatal )?error: |^error(\[E\d+\])?: |error TS\d+: `), after: 5},
+	{name: "npm", pattern: regexp.MustCompile(`^npm (ERR!|error) `)},
+	{name: "pytest", pattern: regexp.MustCompile(`^(FAILED|ERROR) \S|^E\s+\S|^_{3,} .+ _{3,}$|^=+ (FAILURES|ERRORS|short test summary info) =+$`)},
+}
+
+// ExcerptOptions configures how excerpts of output are extracted ; zero values use defaults.
+type ExcerptOptions struct {
+	// TailLines is the number of last lines always kept, DefaultExcerptTailLines if not set.
+	TailLines int
+	// ContextLines is the number of lines kept before and after interesting lines, DefaultExcerptContextLines if not set.
+	ContextLines int
+	// MaxLines is the maximum number of lines kept around interesting lines, DefaultExcerptMaxLines if not set.
+	MaxLines int
+	// Patterns are regular expressions matching additional interesting lines.
+	Patterns []string
+}
+
+// ExcerptExtractor extracts excerpts of output, keeping its last lines along with regions around interesting lines.
+type ExcerptExtractor struct {
+	tailLines    int
+	contextLines int
+	maxLines     int
+	detectors    []*excerptDetector
+}
+
+// Excerpt is a part of output that is most likely to explain a failure.
+type Excerpt struct {
+	// Text of the excerpt, with omitted parts of output replaced by a line describing how many lines were omitted.
+	Text string `json:"text"`
+	// Detectors lists names of detectors that matched lines of output.
+	Detectors []string `json:"detectors,omitempty"`
+	// TotalLines is the number of lines in the whole output.
+	TotalLines int `json:"total_lines"`
+	// OmittedLines is the number of lines of output that are not included in the excerpt.
+	OmittedLines int `json:"omitted_lines"`
+}
+
+// NewExcerptExtractor creates an ExcerptExtractor with built-in detectors and detectors for additional patterns.
+func NewExcerptExtractor(opts ExcerptOptions) (*ExcerptExtractor, error) {
+	e := &ExcerptExtractor{
+		tailLines:    opts.TailLines,
+		contextLines: opts.ContextLines,
+		maxLines:     opts.MaxLines,
+		detectors:    append([]*excerptDetector{}, builtinExcerptDetectors...),
+	}
+	if e.tailLines <= 0 {
+		e.tailLines = DefaultExcerptTailLines
+	}
+	if e.contextLines <= 0 {
+		e.contextLines = DefaultExcerptContextLines
+	}
+	if e.maxLines <= 0 {
+		e.maxLines = DefaultExcerptMaxLines
+	}
+
+	for _, pattern := range opts.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid excerpt pattern %q: %w", pattern, err)
+		}
+		e.detectors = append(e.detectors, &excerptDetector{name: "pattern " + pattern, pattern: re})
+	}
+
+	return e, nil
+}
+
+// Extract returns an excerpt of output.
+func (e *ExcerptExtractor) Extract(output string) *Excerpt {
+	w := e.NewWriter()
+	// writing to an ExcerptWriter never fails
+	_, _ = w.Write([]byte(output))
+	return w.Excerpt()
+}
+
+// NewWriter returns an ExcerptWriter that output can be written to as it is being retrieved, so that only
+// the excerpt is kept in memory instead of the whole output.
+func (e *ExcerptExtractor) NewWriter() *ExcerptWriter {
+	return &ExcerptWriter{
+		extractor: e,
+		detected:  map[string]bool{},
+	}
+}
+
+// excerptLine is a line of output along with its index.
+type excerptLine struct {
+	index int
+	text  string
+}
+
+// ExcerptWriter extracts an excerpt of output written to it line by line, see ExcerptExtractor.
+type ExcerptWriter struct {
+	extractor *ExcerptExtractor
+	// partial holds the last line of output written so far if it did not end with a newline yet
+	partial strings.Builder
+	// recent holds the most recent lines that were not kept, for context before interesting lines and the last lines of output
+	recent []excerptLine
+	// kept holds lines around interesting lines
+	kept      []excerptLine
+	keepAfter int
+	lines     int
+	detected  map[string]bool
+	detectors []string
+}
+
+// Write writes output to the excerpt, processing all complete lines.
+func (w *ExcerptWriter) Write(p []byte) (int, error) {
+	data := string(p)
+	for {
+		newline := strings.IndexByte(data, '\n')
+		if newline < 0 {
+			w.partial.WriteString(data)
+			break
+		}
+		w.partial.WriteString(data[:newline])
+		w.addLine(w.partial.String())
+		w.partial.Reset()
+		data = data[newline+1:]
+	}
+	return len(p), nil
+}
+
+// addLine processes a single line of output.
+func (w *ExcerptWriter) addLine(line string) {
+	e := w.extractor
+	// progress output rewrites the line using carriage returns, only keep what would end up being shown
+	line = StripANSI(line)
+	if i := strings.LastIndexByte(strings.TrimRight(line, "\r"), '\r'); i >= 0 {
+		line = line[i+1:]
+	}
+	line = strings.TrimRight(line, "\r")
+
+	current := excerptLine{index: w.lines, text: line}
+	w.lines++
+
+	var detector *excerptDetector
+	for _, d := range e.detectors {
+		if d.pattern.MatchString(line) {
+			detector = d
+			break
+		}
+	}
+
+	if detector != nil && !w.detected[detector.name] {
+		w.detected[detector.name] = true
+		w.detectors = append(w.detectors, detector.name)
+	}
+
+	// once enough lines were kept, further interesting lines are only included if they are among the last lines
+	canKeep := len(w.kept) < e.maxLines
+	switch {
+	case detector != nil && canKeep:
+		// keep context lines before the interesting line that were not kept yet
+		start := len(w.recent) - e.contextLines
+		if start < 0 {
+			start = 0
+		}
+		w.kept = append(w.kept, w.recent[start:]...)
+		w.recent = w.recent[:0]
+		w.kept = append(w.kept, current)
+		w.keepAfter = e.contextLines + detector.after
+	case w.keepAfter > 0 && canKeep:
+		w.kept = append(w.kept, current)
+		w.keepAfter--
+	default:
+		w.recent = append(w.recent, current)
+		// only keep as many recent lines as needed for context or the last lines of output
+		limit := e.tailLines
+		if e.contextLines > limit {
+			limit = e.contextLines
+		}
+		if len(w.recent) > 2*limit {
+			w.recent = append(w.recent[:0], w.recent[len(w.recent)-limit:]...)
+		}
+	}
+}
+
+// Excerpt returns the excerpt of all output written so far, including the last line if it did not end with a newline.
+func (w *ExcerptWriter) Excerpt() *Excerpt {
+	if w.partial.Len() > 0 {
+		w.addLine(w.partial.String())
+		w.partial.Reset()
+	}
+
+	lines := append([]excerptLine{}, w.kept...)
+	tail := w.recent
+	if len(tail) > w.extractor.tailLines {
+		tail = tail[len(tail)-w.extractor.tailLines:]
+	}
+	lines = append(lines, tail...)
+
+	var sb strings.Builder
+	previous := -1
+	for _, line := range lines {
+		if omitted := line.index - previous - 1; omitted > 0 {
+			fmt.Fprintf(&sb, "... %d lines omitted ...\n", omitted)
+		}
+		sb.WriteString(line.text)
+		sb.WriteString("\n")
+		previous = line.index
+	}
+	if omitted := w.lines - previous - 1; omitted > 0 {
+		fmt.Fprintf(&sb, "... %d lines omitted ...\n", omitted)
+	}
+
+	return &Excerpt{
+		Text:         sb.String(),
+		Detectors:    append([]string{}, w.detectors...),
+		TotalLines:   w.lines,
+		OmittedLines: w.lines - len(lines),
+	}
+}
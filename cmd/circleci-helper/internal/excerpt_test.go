@@ -0,0 +1,157 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// numberedLines returns lines "line <from>" to "line <to>", inclusive.
+func numberedLines(from int, to int) []string {
+	var lines []string
+	for i := from; i <= to; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	return lines
+}
+
+func Test_StripANSI(t *testing.T) {
+	input := "\x1b[31mFAIL\x1b[0m \x1b[1;32mok\x1b[m \x1b]8;;https://example.com\x07link\x1b]8;;\x07"
+	if want, got := "FAIL ok link", StripANSI(input); want != got {
+		t.Errorf("invalid result; want %q, got %q", want, got)
+	}
+}
+
+func Test_ExcerptExtractor(t *testing.T) {
+	for _, test := range []struct {
+		name              string
+		opts              ExcerptOptions
+		lines             []string
+		expectedLines     []string
+		expectedDetectors []string
+	}{
+		{
+			name:          "short output",
+			opts:          ExcerptOptions{TailLines: 5},
+			lines:         numberedLines(1, 3),
+			expectedLines: numberedLines(1, 3),
+		},
+		{
+			name:          "tail only",
+			opts:          ExcerptOptions{TailLines: 2},
+			lines:         numberedLines(1, 10),
+			expectedLines: []string{"... 8 lines omitted ...", "line 9", "line 10"},
+		},
+		{
+			name:  "go test failure",
+			opts:  ExcerptOptions{TailLines: 1, ContextLines: 1},
+			lines: append(append(numberedLines(1, 10), "--- FAIL: TestSomething (0.01s)", "    some_test.go:12: \x1b[31mexpected 1\x1b[0m"), numberedLines(11, 20)...),
+			expectedLines: []string{
+				"... 9 lines omitted ...",
+				"line 10",
+				"--- FAIL: TestSomething (0.01s)",
+				"    some_test.go:12: expected 1",
+				"line 11",
+				"... 8 lines omitted ...",
+				"line 20",
+			},
+			expectedDetectors: []string{"go test"},
+		},
+		{
+			name:  "go panic with stack trace",
+			opts:  ExcerptOptions{TailLines: 1, ContextLines: 1},
+			lines: append(append(numberedLines(1, 5), "panic: runtime error: index out of range", "", "goroutine 1 [running]:", "main.main()"), numberedLines(6, 100)...),
+			expectedLines: append(append(
+				[]string{"... 4 lines omitted ...", "line 5", "panic: runtime error: index out of range", "", "goroutine 1 [running]:", "main.main()"},
+				numberedLines(6, 43)...),
+				"... 56 lines omitted ...", "line 100",
+			),
+			expectedDetectors: []string{"go panic"},
+		},
+		{
+			name: "compiler, npm and pytest errors",
+			opts: ExcerptOptions{TailLines: 1, ContextLines: 1},
+			lines: []string{
+				"line 1", "line 2", "main.go:10:5: undefined: foo", "line 3", "line 4", "line 5",
+				"npm ERR! code ELIFECYCLE", "line 6", "line 7", "line 8", "E       assert 1 == 2", "line 9", "line 10", "line 11",
+			},
+			expectedLines: []string{
+				"... 1 lines omitted ...",
+				"line 2", "main.go:10:5: undefined: foo", "line 3",
+				"... 1 lines omitted ...",
+				"line 5", "npm ERR! code ELIFECYCLE", "line 6",
+				"... 1 lines omitted ...",
+				"line 8", "E       assert 1 == 2", "line 9",
+				"... 1 lines omitted ...",
+				"line 11",
+			},
+			expectedDetectors: []string{"go build", "npm", "pytest"},
+		},
+		{
+			name:              "custom pattern",
+			opts:              ExcerptOptions{TailLines: 1, ContextLines: 1, Patterns: []string{`^Error: `}},
+			lines:             append(append(numberedLines(1, 5), "Error: connection refused"), numberedLines(6, 10)...),
+			expectedLines:     []string{"... 4 lines omitted ...", "line 5", "Error: connection refused", "line 6", "... 3 lines omitted ...", "line 10"},
+			expectedDetectors: []string{"pattern ^Error: "},
+		},
+		{
+			name:          "progress output",
+			opts:          ExcerptOptions{TailLines: 2},
+			lines:         []string{"downloading 10%\rdownloading 50%\rdownloading 100%\r", "done"},
+			expectedLines: []string{"downloading 100%", "done"},
+		},
+	} {
+		t.Run(test.name, func(tt *testing.T) {
+			extractor, err := NewExcerptExtractor(test.opts)
+			if err != nil {
+				tt.Fatalf("unexpected error: %v", err)
+			}
+
+			excerpt := extractor.Extract(strings.Join(test.lines, "\n"))
+			if want, got := strings.Join(test.expectedLines, "\n")+"\n", excerpt.Text; want != got {
+				tt.Errorf("invalid excerpt; want:\n%s\ngot:\n%s", want, got)
+			}
+			if want, got := test.expectedDetectors, excerpt.Detectors; len(want) > 0 && !reflect.DeepEqual(want, got) {
+				tt.Errorf("invalid detectors; want %v, got %v", want, got)
+			}
+			if want, got := len(test.lines), excerpt.TotalLines; want != got {
+				tt.Errorf("invalid number of lines; want %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func Test_ExcerptWriter_maxLines(t *testing.T) {
+	extractor, err := NewExcerptExtractor(ExcerptOptions{TailLines: 1, ContextLines: 1, MaxLines: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// write output in chunks that do not end at line boundaries
+	w := extractor.NewWriter()
+	output := strings.Join([]string{"line 1", "FAIL a", "line 2", "line 3", "FAIL b", "line 4", "line 5"}, "\n")
+	for len(output) > 0 {
+		n := 4
+		if n > len(output) {
+			n = len(output)
+		}
+		if _, err := w.Write([]byte(output[:n])); err != nil {
+			t.Fatal(err)
+		}
+		output = output[n:]
+	}
+
+	excerpt := w.Excerpt()
+	want := "line 1\nFAIL a\nline 2\n... 3 lines omitted ...\nline 5\n"
+	if got := excerpt.Text; want != got {
+		t.Errorf("invalid excerpt; want:\n%s\ngot:\n%s", want, got)
+	}
+	if want, got := 3, excerpt.OmittedLines; want != got {
+		t.Errorf("invalid number of omitted lines; want %v, got %v", want, got)
+	}
+
+	if _, err := NewExcerptExtractor(ExcerptOptions{Patterns: []string{"("}}); err == nil {
+		t.Errorf("expected an error for invalid pattern")
+	}
+}
@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+)
+
+// FilterExplanation describes whether a workflow or a job is kept by filters and the rule that decided it.
+type FilterExplanation struct {
+	Name     string `json:"name"`
+	Included bool   `json:"included"`
+	Rule     string `json:"rule"`
+}
+
+// WorkflowFilterExplanation describes filtering of a workflow and all of its jobs.
+type WorkflowFilterExplanation struct {
+	FilterExplanation
+	Jobs []*FilterExplanation `json:"jobs"`
+}
+
+// ExplainFilters reports which of the latest workflows in the pipeline and their jobs are kept by filters in opts, without waiting for them.
+func ExplainFilters(ctx context.Context, client circle.Client, opts WaitForJobsOptions) ([]*WorkflowFilterExplanation, error) {
+	workflowFilter, err := newWorkflowFilter(opts.WorkflowNames, opts.IncludeWorkflows, opts.ExcludeWorkflows)
+	if err != nil {
+		return nil, err
+	}
+
+	jobFilter, err := newJobFilter(opts.ExcludeJobNames, opts.JobPrefixes, opts.IncludeJobs, opts.ExcludeJobs)
+	if err != nil {
+		return nil, err
+	}
+
+	where, err := ParseExpression(opts.Where)
+	if err != nil {
+		return nil, err
+	}
+
+	pipelineID, err := client.GetPipelineID(ctx, opts.ProjectType, opts.Org, opts.Project, opts.PipelineNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	workflows, err := getLatestWorkflows(ctx, client, pipelineID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*WorkflowFilterExplanation
+	for _, workflow := range workflows {
+		included, rule := workflowFilter.Explain(workflow.Name)
+		if included && !where.MatchWorkflow(workflow) {
+			included, rule = false, "did not match --where expression"
+		}
+		explanation := &WorkflowFilterExplanation{
+			FilterExplanation: FilterExplanation{Name: workflow.Name, Included: included, Rule: rule},
+		}
+
+		jobs, err := client.GetWorkflowJobs(ctx, workflow.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, job := range jobs {
+			included, rule := jobFilter.Explain(job.Name)
+			if included && !where.MatchJob(workflow, job) {
+				included, rule = false, "did not match --where expression"
+			}
+			explanation.Jobs = append(explanation.Jobs, &FilterExplanation{Name: job.Name, Included: included, Rule: rule})
+		}
+
+		result = append(result, explanation)
+	}
+
+	return result, nil
+}
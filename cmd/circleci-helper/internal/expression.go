@@ -0,0 +1,602 @@
+package internal
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+)
+
+// Expression is a parsed --where expression that selects workflows and jobs, such as:
+//
+//	workflow.name like "deploy-*" and not job.approval and (job.started_at > "2024-01-01T00:00:00Z" or job.status == "failed")
+//
+// Expressions support comparisons (==, !=, <, <=, >, >=), regular expressions (=~, !~), globs (like),
+// boolean operators (and, or, not, &&, ||, !) and parentheses. Time fields are compared with RFC3339 strings.
+//
+// Workflows are selected without any job, so conditions on job fields are unknown for them. Unknown
+// conditions do not exclude workflows, so that filtering workflows never drops workflows with matching jobs.
+// When selecting jobs, conditions that are still unknown, such as start time of jobs that have not started yet,
+// do not select the job.
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+// exprType describes type of a value in an expression.
+type exprType int
+
+const (
+	exprBool exprType = iota
+	exprString
+	exprNumber
+	exprTime
+)
+
+func (t exprType) String() string {
+	switch t {
+	case exprBool:
+		return "bool"
+	case exprString:
+		return "string"
+	case exprNumber:
+		return "number"
+	default:
+		return "time"
+	}
+}
+
+// exprValue is a value of an expression, which may be unknown if it refers to a job when selecting workflows.
+type exprValue struct {
+	known bool
+	b     bool
+	s     string
+	n     float64
+	t     time.Time
+}
+
+func unknownValue() exprValue { return exprValue{} }
+
+func boolValue(b bool) exprValue { return exprValue{known: true, b: b} }
+
+func stringValue(s string) exprValue { return exprValue{known: true, s: s} }
+
+func numberValue(n float64) exprValue { return exprValue{known: true, n: n} }
+
+// timeValue parses CircleCI timestamp, treating empty values (such as jobs not started yet) as unknown.
+func timeValue(s string) exprValue {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return unknownValue()
+	}
+	return exprValue{known: true, t: t}
+}
+
+// exprEnv is the workflow and job an expression is evaluated for ; job is nil when selecting workflows.
+type exprEnv struct {
+	workflow *circle.Workflow
+	job      *circle.Job
+}
+
+// exprField describes a field of a workflow or a job that can be used in expressions.
+type exprField struct {
+	typ exprType
+	get func(env *exprEnv) exprValue
+}
+
+// helpers to define fields that return unknown values when workflow or job is not available
+func workflowField(typ exprType, get func(workflow *circle.Workflow) exprValue) exprField {
+	return exprField{typ: typ, get: func(env *exprEnv) exprValue {
+		if env.workflow == nil {
+			return unknownValue()
+		}
+		return get(env.workflow)
+	}}
+}
+
+func jobField(typ exprType, get func(job *circle.Job) exprValue) exprField {
+	return exprField{typ: typ, get: func(env *exprEnv) exprValue {
+		if env.job == nil {
+			return unknownValue()
+		}
+		return get(env.job)
+	}}
+}
+
+var exprFields = map[string]exprField{
+	"workflow.id":         workflowField(exprString, func(w *circle.Workflow) exprValue { return stringValue(w.ID) }),
+	"workflow.name":       workflowField(exprString, func(w *circle.Workflow) exprValue { return stringValue(w.Name) }),
+	"workflow.status":     workflowField(exprString, func(w *circle.Workflow) exprValue { return stringValue(w.Status) }),
+	"workflow.created_at": workflowField(exprTime, func(w *circle.Workflow) exprValue { return timeValue(w.CreatedAt) }),
+	"job.id":              jobField(exprString, func(j *circle.Job) exprValue { return stringValue(j.ID) }),
+	"job.name":            jobField(exprString, func(j *circle.Job) exprValue { return stringValue(j.Name) }),
+	"job.status":          jobField(exprString, func(j *circle.Job) exprValue { return stringValue(j.Status) }),
+	"job.type":            jobField(exprString, func(j *circle.Job) exprValue { return stringValue(j.Type) }),
+	"job.number":          jobField(exprNumber, func(j *circle.Job) exprValue { return numberValue(float64(j.JobNumber)) }),
+	"job.approval":        jobField(exprBool, func(j *circle.Job) exprValue { return boolValue(circle.JobIsApproval(j)) }),
+	"job.started_at":      jobField(exprTime, func(j *circle.Job) exprValue { return timeValue(j.StartedAt) }),
+	"job.stopped_at":      jobField(exprTime, func(j *circle.Job) exprValue { return timeValue(j.StoppedAt) }),
+}
+
+// exprNode is a single node of a parsed expression.
+type exprNode interface {
+	typ() exprType
+	eval(env *exprEnv) exprValue
+}
+
+type literalNode struct {
+	t exprType
+	v exprValue
+}
+
+func (n *literalNode) typ() exprType               { return n.t }
+func (n *literalNode) eval(env *exprEnv) exprValue { return n.v }
+
+type fieldNode struct {
+	field exprField
+}
+
+func (n *fieldNode) typ() exprType               { return n.field.typ }
+func (n *fieldNode) eval(env *exprEnv) exprValue { return n.field.get(env) }
+
+type notNode struct {
+	operand exprNode
+}
+
+func (n *notNode) typ() exprType { return exprBool }
+func (n *notNode) eval(env *exprEnv) exprValue {
+	v := n.operand.eval(env)
+	if !v.known {
+		return v
+	}
+	return boolValue(!v.b)
+}
+
+// logicalNode implements and / or using three-valued logic.
+type logicalNode struct {
+	and         bool
+	left, right exprNode
+}
+
+func (n *logicalNode) typ() exprType { return exprBool }
+func (n *logicalNode) eval(env *exprEnv) exprValue {
+	l, r := n.left.eval(env), n.right.eval(env)
+	// for "and", any false operand makes the result false ; for "or", any true operand makes the result true
+	if (l.known && l.b != n.and) || (r.known && r.b != n.and) {
+		return boolValue(!n.and)
+	}
+	if !l.known || !r.known {
+		return unknownValue()
+	}
+	return boolValue(n.and)
+}
+
+type compareNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *compareNode) typ() exprType { return exprBool }
+func (n *compareNode) eval(env *exprEnv) exprValue {
+	l, r := n.left.eval(env), n.right.eval(env)
+	if !l.known || !r.known {
+		return unknownValue()
+	}
+
+	// compare values, resulting in -1, 0 or 1
+	var c int
+	switch n.left.typ() {
+	case exprString:
+		c = strings.Compare(l.s, r.s)
+	case exprNumber:
+		c = compareOrdered(l.n, r.n)
+	case exprTime:
+		c = l.t.Compare(r.t)
+	case exprBool:
+		if l.b != r.b {
+			c = 1
+		}
+	}
+
+	switch n.op {
+	case "==":
+		return boolValue(c == 0)
+	case "!=":
+		return boolValue(c != 0)
+	case "<":
+		return boolValue(c < 0)
+	case "<=":
+		return boolValue(c <= 0)
+	case ">":
+		return boolValue(c > 0)
+	default:
+		return boolValue(c >= 0)
+	}
+}
+
+func compareOrdered(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// matchNode implements =~, !~ and like operators.
+type matchNode struct {
+	negate  bool
+	operand exprNode
+	match   func(s string) bool
+}
+
+func (n *matchNode) typ() exprType { return exprBool }
+func (n *matchNode) eval(env *exprEnv) exprValue {
+	v := n.operand.eval(env)
+	if !v.known {
+		return v
+	}
+	return boolValue(n.match(v.s) != n.negate)
+}
+
+// ParseExpression parses an expression, returning nil if the expression is empty.
+func ParseExpression(source string) (*Expression, error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, nil
+	}
+
+	tokens, err := tokenizeExpression(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{source: source, tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorf(tok, "unexpected %s", tok)
+	}
+	if root.typ() != exprBool {
+		return nil, fmt.Errorf("invalid expression %q: expression must be a condition, got %s", source, root.typ())
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns source of the expression.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// MatchWorkflow returns whether the workflow may be selected by the expression ; conditions on jobs are ignored.
+func (e *Expression) MatchWorkflow(workflow *circle.Workflow) bool {
+	if e == nil {
+		return true
+	}
+	v := e.root.eval(&exprEnv{workflow: workflow})
+	return !v.known || v.b
+}
+
+// MatchJob returns whether the job in specified workflow is selected by the expression ; jobs are not selected
+// if the result is unknown.
+func (e *Expression) MatchJob(workflow *circle.Workflow, job *circle.Job) bool {
+	if e == nil {
+		return true
+	}
+	v := e.root.eval(&exprEnv{workflow: workflow, job: job})
+	return v.known && v.b
+}
+
+// kinds of tokens in expressions
+const (
+	tokenEOF = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type exprToken struct {
+	kind  int
+	value string
+	pos   int
+}
+
+func (t exprToken) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(t.value)
+	}
+	return fmt.Sprintf("%q", t.value)
+}
+
+// operators, sorted so that longer operators are matched first
+var exprOperators = []string{"==", "!=", "<=", ">=", "=~", "!~", "&&", "||", "<", ">", "!", "(", ")"}
+
+func tokenizeExpression(source string) ([]exprToken, error) {
+	var tokens []exprToken
+	i := 0
+	for i < len(source) {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			// find the closing quote, skipping escaped characters
+			end := i + 1
+			for end < len(source) && rune(source[end]) != c {
+				if source[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(source) {
+				return nil, fmt.Errorf("invalid expression %q at position %d: unterminated string", source, i+1)
+			}
+			value := source[i+1 : end]
+			if c == '"' {
+				unquoted, err := strconv.Unquote(source[i : end+1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid expression %q at position %d: invalid string: %v", source, i+1, err)
+				}
+				value = unquoted
+			} else {
+				value = strings.ReplaceAll(value, `\'`, `'`)
+			}
+			tokens = append(tokens, exprToken{kind: tokenString, value: value, pos: i})
+			i = end + 1
+		case unicode.IsDigit(c):
+			end := i
+			for end < len(source) && (unicode.IsDigit(rune(source[end])) || source[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, exprToken{kind: tokenNumber, value: source[i:end], pos: i})
+			i = end
+		case unicode.IsLetter(c) || c == '_':
+			end := i
+			for end < len(source) && (unicode.IsLetter(rune(source[end])) || unicode.IsDigit(rune(source[end])) || source[end] == '_' || source[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, value: source[i:end], pos: i})
+			i = end
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, exprToken{kind: tokenOperator, value: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("invalid expression %q at position %d: unexpected character %q", source, i+1, c)
+			}
+		}
+	}
+	return append(tokens, exprToken{kind: tokenEOF, pos: len(source)}), nil
+}
+
+// exprParser is a recursive descent parser for expressions.
+type exprParser struct {
+	source string
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of specified operators or keywords.
+func (p *exprParser) accept(values ...string) (exprToken, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOperator && tok.kind != tokenIdent {
+		return tok, false
+	}
+	for _, v := range values {
+		if tok.value == v {
+			return p.next(), true
+		}
+	}
+	return tok, false
+}
+
+func (p *exprParser) errorf(tok exprToken, format string, args ...interface{}) error {
+	return fmt.Errorf("invalid expression %q at position %d: %s", p.source, tok.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *exprParser) expectBool(tok exprToken, node exprNode, operator string) error {
+	if node.typ() != exprBool {
+		return p.errorf(tok, "%s requires conditions, got %s", operator, node.typ())
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	return p.parseLogical(false, p.parseAnd, "or", "||")
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	return p.parseLogical(true, p.parseNot, "and", "&&")
+}
+
+func (p *exprParser) parseLogical(and bool, parseOperand func() (exprNode, error), operators ...string) (exprNode, error) {
+	start := p.peek()
+	left, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.accept(operators...)
+		if !ok {
+			return left, nil
+		}
+		if err := p.expectBool(start, left, tok.value); err != nil {
+			return nil, err
+		}
+		start = p.peek()
+		right, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectBool(start, right, tok.value); err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: and, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if tok, ok := p.accept("not", "!"); ok {
+		start := p.peek()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectBool(start, operand, tok.value); err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	tok, ok := p.accept("==", "!=", "<", "<=", ">", ">=", "=~", "!~", "like")
+	if !ok {
+		return left, nil
+	}
+
+	rightTok := p.peek()
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok.value {
+	case "=~", "!~", "like":
+		literal, isLiteral := right.(*literalNode)
+		if left.typ() != exprString || !isLiteral || literal.t != exprString {
+			return nil, p.errorf(tok, "%s requires a string field and a string literal", tok.value)
+		}
+		if tok.value == "like" {
+			if _, err := path.Match(literal.v.s, ""); err != nil {
+				return nil, p.errorf(rightTok, "invalid glob pattern %q: %v", literal.v.s, err)
+			}
+			pattern := literal.v.s
+			return &matchNode{operand: left, match: func(s string) bool {
+				matched, _ := path.Match(pattern, s)
+				return matched
+			}}, nil
+		}
+		re, err := regexp.Compile(literal.v.s)
+		if err != nil {
+			return nil, p.errorf(rightTok, "invalid regular expression %q: %v", literal.v.s, err)
+		}
+		return &matchNode{negate: tok.value == "!~", operand: left, match: re.MatchString}, nil
+	}
+
+	// allow comparing time fields with strings, which are converted to times
+	left, err = p.convertTimeLiteral(left, right)
+	if err != nil {
+		return nil, err
+	}
+	right, err = p.convertTimeLiteral(right, left)
+	if err != nil {
+		return nil, err
+	}
+
+	if left.typ() != right.typ() {
+		return nil, p.errorf(tok, "cannot compare %s with %s", left.typ(), right.typ())
+	}
+	if left.typ() == exprBool && tok.value != "==" && tok.value != "!=" {
+		return nil, p.errorf(tok, "%s is not supported for conditions", tok.value)
+	}
+
+	return &compareNode{op: tok.value, left: left, right: right}, nil
+}
+
+// convertTimeLiteral converts node to a time literal if it is a string literal compared to a time.
+func (p *exprParser) convertTimeLiteral(node exprNode, other exprNode) (exprNode, error) {
+	literal, ok := node.(*literalNode)
+	if !ok || literal.t != exprString || other.typ() != exprTime {
+		return node, nil
+	}
+	t, err := time.Parse(time.RFC3339, literal.v.s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %q is not a valid RFC3339 time", p.source, literal.v.s)
+	}
+	return &literalNode{t: exprTime, v: exprValue{known: true, t: t}}, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return &literalNode{t: exprString, v: stringValue(tok.value)}, nil
+	case tokenNumber:
+		n, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, p.errorf(tok, "invalid number %s", tok)
+		}
+		return &literalNode{t: exprNumber, v: numberValue(n)}, nil
+	case tokenIdent:
+		switch tok.value {
+		case "true", "false":
+			return &literalNode{t: exprBool, v: boolValue(tok.value == "true")}, nil
+		case "and", "or", "not", "like":
+			return nil, p.errorf(tok, "unexpected %s", tok)
+		}
+		field, ok := exprFields[tok.value]
+		if !ok {
+			return nil, p.errorf(tok, "unknown field %s, valid fields are: %s", tok, strings.Join(exprFieldNames(), ", "))
+		}
+		return &fieldNode{field: field}, nil
+	case tokenOperator:
+		if tok.value == "(" {
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if closing := p.next(); closing.value != ")" || closing.kind != tokenOperator {
+				return nil, p.errorf(closing, "expected \")\", got %s", closing)
+			}
+			return node, nil
+		}
+	}
+	return nil, p.errorf(tok, "unexpected %s", tok)
+}
+
+func exprFieldNames() []string {
+	var names []string
+	for name := range exprFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+)
+
+func Test_Expression(t *testing.T) {
+	deploy := &circle.Workflow{Name: "deploy-prod", Status: "running", CreatedAt: "2024-01-02T00:00:00Z"}
+	build := &circle.Workflow{Name: "build", Status: "success", CreatedAt: "2024-01-01T00:00:00Z"}
+	approval := &circle.Job{Name: "hold", Type: "approval", Status: "on_hold"}
+	failed := &circle.Job{Name: "test-1", Type: "build", Status: "failed", JobNumber: 12, StartedAt: "2024-01-01T10:00:00Z"}
+	late := &circle.Job{Name: "test-2", Type: "build", Status: "running", JobNumber: 13, StartedAt: "2024-01-03T10:00:00Z"}
+	notStarted := &circle.Job{Name: "test-3", Type: "build", Status: "blocked", JobNumber: 14}
+
+	for _, test := range []struct {
+		expression string
+		workflow   *circle.Workflow
+		job        *circle.Job
+		expect     bool
+	}{
+		{expression: `workflow.name == "build"`, workflow: build, expect: true},
+		{expression: `workflow.name == "build"`, workflow: deploy, expect: false},
+		{expression: `workflow.name like "deploy-*"`, workflow: deploy, expect: true},
+		{expression: `workflow.name =~ '^deploy-(prod|staging)$'`, workflow: deploy, expect: true},
+		{expression: `workflow.name !~ "^deploy"`, workflow: deploy, expect: false},
+		{expression: `workflow.created_at >= "2024-01-02T00:00:00Z"`, workflow: build, expect: false},
+		// conditions on jobs are unknown when selecting workflows, so they do not exclude workflows
+		{expression: `workflow.name like "deploy-*" and job.status == "failed"`, workflow: deploy, expect: true},
+		{expression: `workflow.name like "deploy-*" and job.status == "failed"`, workflow: build, expect: false},
+		{expression: `not job.approval`, workflow: deploy, expect: true},
+		{expression: `not job.approval`, workflow: deploy, job: approval, expect: false},
+		{expression: `not job.approval`, workflow: deploy, job: failed, expect: true},
+		{
+			expression: `workflow.name like "deploy-*" and not job.approval and (job.started_at > "2024-01-02T00:00:00Z" or job.status == "failed")`,
+			workflow:   deploy, job: failed, expect: true,
+		},
+		{
+			expression: `workflow.name like "deploy-*" and not job.approval and (job.started_at > "2024-01-02T00:00:00Z" or job.status == "failed")`,
+			workflow:   deploy, job: late, expect: true,
+		},
+		{
+			expression: `workflow.name like "deploy-*" && !job.approval && (job.started_at > "2024-01-02T00:00:00Z" || job.status == "failed")`,
+			workflow:   deploy, job: approval, expect: false,
+		},
+		// jobs that have not started yet have unknown start time, so conditions on it do not select them
+		{expression: `job.started_at > "2024-01-02T00:00:00Z"`, workflow: deploy, job: notStarted, expect: false},
+		{expression: `not (job.started_at > "2024-01-02T00:00:00Z")`, workflow: deploy, job: notStarted, expect: false},
+		{expression: `job.started_at > "2024-01-02T00:00:00Z" or job.status == "blocked"`, workflow: deploy, job: notStarted, expect: true},
+		{expression: `job.number >= 13`, workflow: deploy, job: failed, expect: false},
+		{expression: `job.number >= 13`, workflow: deploy, job: late, expect: true},
+		{expression: `job.approval == false`, workflow: deploy, job: late, expect: true},
+	} {
+		t.Run(test.expression, func(tt *testing.T) {
+			e, err := ParseExpression(test.expression)
+			if err != nil {
+				tt.Fatalf("unexpected error: %v", err)
+			}
+			var got bool
+			if test.job == nil {
+				got = e.MatchWorkflow(test.workflow)
+			} else {
+				got = e.MatchJob(test.workflow, test.job)
+			}
+			if want := test.expect; want != got {
+				tt.Errorf("invalid result; want %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func Test_Expression_nil(t *testing.T) {
+	e, err := ParseExpression("  ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e != nil {
+		t.Fatalf("expected nil expression")
+	}
+	if !e.MatchWorkflow(&circle.Workflow{}) || !e.MatchJob(&circle.Workflow{}, &circle.Job{}) {
+		t.Errorf("nil expression should match everything")
+	}
+}
+
+func Test_Expression_errors(t *testing.T) {
+	for _, test := range []struct {
+		expression string
+		message    string
+	}{
+		{expression: `job.nme == "x"`, message: "unknown field \"job.nme\""},
+		{expression: `job.name == 1`, message: "cannot compare string with number"},
+		{expression: `job.name`, message: "expression must be a condition"},
+		{expression: `job.name == "x" and`, message: "unexpected end of expression"},
+		{expression: `(job.name == "x"`, message: "expected \")\""},
+		{expression: `job.name == "x`, message: "unterminated string"},
+		{expression: `job.name =~ "("`, message: "invalid regular expression"},
+		{expression: `job.started_at > "yesterday"`, message: "not a valid RFC3339 time"},
+		{expression: `job.name == "x" job.status`, message: "at position 17: unexpected \"job.status\""},
+		{expression: `job.number and job.approval`, message: "and requires conditions, got number"},
+		{expression: `job.name # "x"`, message: "unexpected character '#'"},
+	} {
+		t.Run(test.expression, func(tt *testing.T) {
+			_, err := ParseExpression(test.expression)
+			if err == nil {
+				tt.Fatalf("expected an error")
+			}
+			if !strings.Contains(err.Error(), test.message) {
+				tt.Errorf("invalid error; want %q in %q", test.message, err.Error())
+			}
+		})
+	}
+}
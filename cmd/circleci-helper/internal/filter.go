@@ -1,12 +1,158 @@
 package internal
 
 import (
+	"fmt"
+	"path"
+	"regexp"
 	"sort"
 	"strings"
 
 	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
 )
 
+// regexPatternPrefix is the prefix for name patterns that are regular expressions.
+const regexPatternPrefix = "re:"
+
+// NamePattern matches names of workflows or jobs.
+type NamePattern struct {
+	description string
+	match       func(name string) bool
+}
+
+// ParseNamePattern parses a pattern, which is a regular expression if prefixed with "re:" and a glob otherwise.
+// Globs use path.Match syntax, so names without any of *, ? or [ characters are matched exactly.
+func ParseNamePattern(pattern string) (*NamePattern, error) {
+	if strings.HasPrefix(pattern, regexPatternPrefix) {
+		re, err := regexp.Compile(strings.TrimPrefix(pattern, regexPatternPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression in pattern %q: %w", pattern, err)
+		}
+		return &NamePattern{description: pattern, match: re.MatchString}, nil
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+	}
+	return &NamePattern{
+		description: pattern,
+		match: func(name string) bool {
+			matched, _ := path.Match(pattern, name)
+			return matched
+		},
+	}, nil
+}
+
+// exactNamePattern returns a pattern that matches specified name only.
+func exactNamePattern(value string) *NamePattern {
+	return &NamePattern{
+		description: value,
+		match:       func(name string) bool { return name == value },
+	}
+}
+
+// prefixNamePattern returns a pattern that matches names starting with specified prefix.
+func prefixNamePattern(prefix string) *NamePattern {
+	return &NamePattern{
+		description: "prefix " + prefix,
+		match:       func(name string) bool { return strings.HasPrefix(name, prefix) },
+	}
+}
+
+// Match returns whether name matches the pattern.
+func (p *NamePattern) Match(name string) bool {
+	return p.match(name)
+}
+
+// String returns description of the pattern.
+func (p *NamePattern) String() string {
+	return p.description
+}
+
+// NameFilter keeps names that match at least one of Include patterns (or any name if there are none)
+// and do not match any of Exclude patterns.
+type NameFilter struct {
+	Include []*NamePattern
+	Exclude []*NamePattern
+}
+
+// NewNameFilter creates a NameFilter from include and exclude patterns, parsing them with ParseNamePattern.
+func NewNameFilter(include []string, exclude []string) (*NameFilter, error) {
+	f := &NameFilter{}
+	for _, pattern := range include {
+		p, err := ParseNamePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.Include = append(f.Include, p)
+	}
+	for _, pattern := range exclude {
+		p, err := ParseNamePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.Exclude = append(f.Exclude, p)
+	}
+	return f, nil
+}
+
+// Match returns whether name should be kept.
+func (f *NameFilter) Match(name string) bool {
+	matches, _ := f.Explain(name)
+	return matches
+}
+
+// Explain returns whether name should be kept along with description of the rule that decided it.
+func (f *NameFilter) Explain(name string) (bool, string) {
+	rule := "no include patterns"
+	if len(f.Include) > 0 {
+		rule = ""
+		for _, p := range f.Include {
+			if p.Match(name) {
+				rule = fmt.Sprintf("matched include pattern %q", p)
+				break
+			}
+		}
+		if rule == "" {
+			return false, "did not match any include pattern"
+		}
+	}
+
+	for _, p := range f.Exclude {
+		if p.Match(name) {
+			return false, fmt.Sprintf("matched exclude pattern %q", p)
+		}
+	}
+
+	return true, rule
+}
+
+// newWorkflowFilter creates a filter for workflows, with exact workflow names being included along with include patterns.
+func newWorkflowFilter(workflowNames []string, includeWorkflows []string, excludeWorkflows []string) (*NameFilter, error) {
+	f, err := NewNameFilter(includeWorkflows, excludeWorkflows)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range workflowNames {
+		f.Include = append(f.Include, exactNamePattern(name))
+	}
+	return f, nil
+}
+
+// newJobFilter creates a filter for jobs, with job prefixes being included and exact job names being excluded along with patterns.
+func newJobFilter(excludeJobNames []string, jobPrefixes []string, includeJobs []string, excludeJobs []string) (*NameFilter, error) {
+	f, err := NewNameFilter(includeJobs, excludeJobs)
+	if err != nil {
+		return nil, err
+	}
+	for _, prefix := range jobPrefixes {
+		f.Include = append(f.Include, prefixNamePattern(prefix))
+	}
+	for _, name := range excludeJobNames {
+		f.Exclude = append(f.Exclude, exactNamePattern(name))
+	}
+	return f, nil
+}
+
 // de-duplicate multiple workflows with same name, only picking up most recent workflow with the name
 // this is required to allow retrying CircleCI workflows or jobs and only retrieving latest result
 func uniqueWorkflows(workflows []*circle.Workflow) []*circle.Workflow {
@@ -30,49 +176,33 @@ func uniqueWorkflows(workflows []*circle.Workflow) []*circle.Workflow {
 	return result
 }
 
-func filterWorkflow(workflow *circle.Workflow, keepNames []string) bool {
-	if len(keepNames) == 0 {
-		return true
-	}
-
-	for _, workflowName := range keepNames {
-		if workflow.Name == workflowName {
-			return true
-		}
+// filterWorkflowWrapper returns a callback that keeps workflows with specified names, or all workflows if none are specified.
+func filterWorkflowWrapper(keepNames []string) func(workflow *circle.Workflow) bool {
+	// exact names and prefixes cannot be invalid, so errors do not need to be checked
+	f, _ := newWorkflowFilter(keepNames, nil, nil)
+	return func(workflow *circle.Workflow) bool {
+		return f.Match(workflow.Name)
 	}
-
-	return false
 }
 
-func filterJob(job *circle.Job, excludeJobNames []string, jobPrefixes []string) bool {
-	matches := true
-	if len(jobPrefixes) > 0 {
-		// when limiting to prefixes, ensure at least one job has same prefix, otherwise ignore job
-		matches = false
-		for _, jobPrefix := range jobPrefixes {
-			if strings.HasPrefix(job.Name, jobPrefix) {
-				matches = true
-				break
-			}
-		}
-	}
-	for _, excludeJobName := range excludeJobNames {
-		if job.Name == excludeJobName {
-			matches = false
-			break
-		}
+// filterJobWrapper returns a callback that keeps jobs with specified prefixes, if any, except for jobs with excluded names.
+func filterJobWrapper(excludeJobNames []string, jobPrefixes []string) func(job *circle.Job) bool {
+	f, _ := newJobFilter(excludeJobNames, jobPrefixes, nil, nil)
+	return func(job *circle.Job) bool {
+		return f.Match(job.Name)
 	}
-	return matches
 }
 
-func filterWorkflowWrapper(keepNames []string) func(workflow *circle.Workflow) bool {
+// filterWorkflowByName returns a callback that filters workflows using specified filter and, optionally, an expression.
+func filterWorkflowByName(f *NameFilter, where *Expression) func(workflow *circle.Workflow) bool {
 	return func(workflow *circle.Workflow) bool {
-		return filterWorkflow(workflow, keepNames)
+		return f.Match(workflow.Name) && where.MatchWorkflow(workflow)
 	}
 }
 
-func filterJobWrapper(excludeJobNames []string, jobPrefixes []string) func(job *circle.Job) bool {
-	return func(job *circle.Job) bool {
-		return filterJob(job, excludeJobNames, jobPrefixes)
+// filterJobByName returns a callback that filters jobs using specified filter and, optionally, an expression.
+func filterJobByName(f *NameFilter, where *Expression) func(workflow *circle.Workflow, job *circle.Job) bool {
+	return func(workflow *circle.Workflow, job *circle.Job) bool {
+		return f.Match(job.Name) && where.MatchJob(workflow, job)
 	}
 }
@@ -98,3 +98,71 @@ func Test_filterJobWrapper(t *testing.T) {
 		})
 	}
 }
+
+func Test_NameFilter(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		include []string
+		exclude []string
+		value   string
+		expect  bool
+		rule    string
+	}{
+		{
+			name:   "no patterns",
+			value:  "test-go-1.22-amd64",
+			expect: true,
+			rule:   "no include patterns",
+		},
+		{
+			name:    "glob include",
+			include: []string{"test-go-*-amd64"},
+			value:   "test-go-1.22-amd64",
+			expect:  true,
+			rule:    `matched include pattern "test-go-*-amd64"`,
+		},
+		{
+			name:    "glob include not matching",
+			include: []string{"test-go-*-arm64"},
+			value:   "test-go-1.22-amd64",
+			expect:  false,
+			rule:    "did not match any include pattern",
+		},
+		{
+			name:    "regex exclude",
+			exclude: []string{`re:^test-go-1\.2[0-2]-`},
+			value:   "test-go-1.22-amd64",
+			expect:  false,
+			rule:    `matched exclude pattern "re:^test-go-1\\.2[0-2]-"`,
+		},
+		{
+			name:    "exact include with exclude not matching",
+			include: []string{"build"},
+			exclude: []string{"re:^test"},
+			value:   "build",
+			expect:  true,
+			rule:    `matched include pattern "build"`,
+		},
+	} {
+		t.Run(test.name, func(tt *testing.T) {
+			f, err := NewNameFilter(test.include, test.exclude)
+			if err != nil {
+				tt.Fatalf("unexpected error: %v", err)
+			}
+			matches, rule := f.Explain(test.value)
+			if want, got := test.expect, matches; want != got {
+				tt.Errorf("invalid result; want %v, got %v", want, got)
+			}
+			if want, got := test.rule, rule; want != got {
+				tt.Errorf("invalid rule; want %v, got %v", want, got)
+			}
+		})
+	}
+
+	// invalid patterns should be reported
+	for _, pattern := range []string{"re:(", "test-["} {
+		if _, err := NewNameFilter([]string{pattern}, nil); err == nil {
+			t.Errorf("expected an error for pattern %q", pattern)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// modes for reporting to GitHub
+const (
+	GitHubModeStatus = "status"
+	GitHubModeCheck  = "check"
+)
+
+// DefaultGitHubAPIURL is the API base URL for github.com.
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// GitHubOptions allows configuring how results are reported to GitHub.
+type GitHubOptions struct {
+	// APIURL is the base URL of GitHub API, such as https://github.example.com/api/v3 for GitHub Enterprise Server.
+	APIURL string
+	Token  string
+	Owner  string
+	Repo   string
+	// Mode is either GitHubModeStatus for commit statuses or GitHubModeCheck for check runs.
+	Mode string
+	// Name is the context of the commit status or name of the check run.
+	Name string
+	// TargetURL is the URL that the status or check run links to.
+	TargetURL string
+}
+
+// GitHubReporter reports state of CircleCI workflows as a single GitHub commit status or check run.
+type GitHubReporter struct {
+	logger     *zap.Logger
+	opts       GitHubOptions
+	revision   string
+	checkRunID int64
+}
+
+// helper to serialize and deserialize GitHub API check runs
+type gitHubCheckRun struct {
+	ID          int64                 `json:"id,omitempty"`
+	Name        string                `json:"name,omitempty"`
+	HeadSHA     string                `json:"head_sha,omitempty"`
+	DetailsURL  string                `json:"details_url,omitempty"`
+	Status      string                `json:"status,omitempty"`
+	Conclusion  string                `json:"conclusion,omitempty"`
+	StartedAt   string                `json:"started_at,omitempty"`
+	CompletedAt string                `json:"completed_at,omitempty"`
+	Output      *gitHubCheckRunOutput `json:"output,omitempty"`
+}
+
+type gitHubCheckRunOutput struct {
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+}
+
+// helper to serialize GitHub API commit statuses
+type gitHubCommitStatus struct {
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url,omitempty"`
+	Description string `json:"description,omitempty"`
+	Context     string `json:"context"`
+}
+
+// NewGitHubReporter creates a new instance of GitHubReporter for specified revision.
+func NewGitHubReporter(logger *zap.Logger, opts GitHubOptions, revision string) (*GitHubReporter, error) {
+	if opts.Mode != GitHubModeStatus && opts.Mode != GitHubModeCheck {
+		return nil, fmt.Errorf("invalid GitHub report mode %q, must be %s or %s", opts.Mode, GitHubModeStatus, GitHubModeCheck)
+	}
+	if opts.Token == "" {
+		return nil, fmt.Errorf("GitHub token must be specified")
+	}
+	if opts.Owner == "" || opts.Repo == "" {
+		return nil, fmt.Errorf("GitHub repository must be specified")
+	}
+	if revision == "" {
+		return nil, fmt.Errorf("pipeline does not have a VCS revision")
+	}
+	if opts.APIURL == "" {
+		opts.APIURL = DefaultGitHubAPIURL
+	}
+	opts.APIURL = strings.TrimSuffix(opts.APIURL, "/")
+
+	return &GitHubReporter{
+		logger:   logger,
+		opts:     opts,
+		revision: revision,
+	}, nil
+}
+
+// GitHubRepositoryFromURL returns owner and repository name from a GitHub repository URL.
+func GitHubRepositoryFromURL(repositoryURL string) (string, string, bool) {
+	u, err := url.Parse(repositoryURL)
+	if err != nil {
+		return "", "", false
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+
+	return parts[0], strings.TrimSuffix(parts[1], ".git"), true
+}
+
+// Start reports that waiting for workflows has started.
+func (r *GitHubReporter) Start(ctx context.Context) error {
+	if r.opts.Mode == GitHubModeStatus {
+		return r.postStatus(ctx, "pending", "Waiting for CircleCI workflows to finish")
+	}
+
+	var response gitHubCheckRun
+	err := r.do(ctx, "POST", fmt.Sprintf("/repos/%s/%s/check-runs", url.PathEscape(r.opts.Owner), url.PathEscape(r.opts.Repo)), &gitHubCheckRun{
+		Name:       r.opts.Name,
+		HeadSHA:    r.revision,
+		DetailsURL: r.opts.TargetURL,
+		Status:     "in_progress",
+		StartedAt:  time.Now().UTC().Format(time.RFC3339),
+	}, &response)
+	if err != nil {
+		return err
+	}
+
+	r.checkRunID = response.ID
+	return nil
+}
+
+// Finish reports the final outcome, using summary, if available, to describe failures.
+func (r *GitHubReporter) Finish(ctx context.Context, outcome string, summary *WorkflowsSummary) error {
+	title, details := describeOutcome(outcome, summary)
+
+	if r.opts.Mode == GitHubModeStatus {
+		state := "error"
+		switch outcome {
+		case OutcomeSuccess:
+			state = "success"
+		case OutcomeFailure:
+			state = "failure"
+		}
+		return r.postStatus(ctx, state, title)
+	}
+
+	conclusion := "failure"
+	switch outcome {
+	case OutcomeSuccess:
+		conclusion = "success"
+	case OutcomeTimeout:
+		conclusion = "timed_out"
+	}
+
+	checkRun := &gitHubCheckRun{
+		Name:        r.opts.Name,
+		HeadSHA:     r.revision,
+		DetailsURL:  r.opts.TargetURL,
+		Status:      "completed",
+		Conclusion:  conclusion,
+		CompletedAt: time.Now().UTC().Format(time.RFC3339),
+		Output: &gitHubCheckRunOutput{
+			Title:   title,
+			Summary: details,
+		},
+	}
+
+	// if the check run was not created by Start, create a completed one
+	if r.checkRunID == 0 {
+		return r.do(ctx, "POST", fmt.Sprintf("/repos/%s/%s/check-runs", url.PathEscape(r.opts.Owner), url.PathEscape(r.opts.Repo)), checkRun, nil)
+	}
+
+	return r.do(ctx, "PATCH", fmt.Sprintf("/repos/%s/%s/check-runs/%d", url.PathEscape(r.opts.Owner), url.PathEscape(r.opts.Repo), r.checkRunID), checkRun, nil)
+}
+
+func (r *GitHubReporter) postStatus(ctx context.Context, state string, description string) error {
+	// GitHub limits description of commit statuses to 140 characters
+	if len(description) > 140 {
+		description = description[:137] + "..."
+	}
+
+	return r.do(ctx, "POST", fmt.Sprintf("/repos/%s/%s/statuses/%s", url.PathEscape(r.opts.Owner), url.PathEscape(r.opts.Repo), url.PathEscape(r.revision)), &gitHubCommitStatus{
+		State:       state,
+		TargetURL:   r.opts.TargetURL,
+		Description: description,
+		Context:     r.opts.Name,
+	}, nil)
+}
+
+func (r *GitHubReporter) do(ctx context.Context, method string, path string, body interface{}, response interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, r.opts.APIURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+r.opts.Token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		return fmt.Errorf("%s %s failed with HTTP response code: %d", method, path, res.StatusCode)
+	}
+
+	if response != nil {
+		return json.NewDecoder(res.Body).Decode(response)
+	}
+
+	return nil
+}
+
+// describeOutcome returns a short title and a markdown summary listing failed workflows and jobs.
+func describeOutcome(outcome string, summary *WorkflowsSummary) (string, string) {
+	var title string
+	switch outcome {
+	case OutcomeSuccess:
+		title = "All CircleCI workflows and jobs finished successfully"
+	case OutcomeFailure:
+		title = "One or more CircleCI workflows or jobs failed"
+	case OutcomeTimeout:
+		title = "Timed out waiting for CircleCI workflows"
+	default:
+		title = "Unable to retrieve status of CircleCI workflows"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(title)
+	sb.WriteString("\n")
+	if summary != nil {
+		for _, workflow := range summary.FailedWorkflows {
+			fmt.Fprintf(&sb, "\n* workflow `%s` failed (status: %s)", workflow.Workflow.Name, workflow.Workflow.Status)
+			describeFailedJobs(&sb, workflow)
+		}
+		for _, workflow := range summary.PendingWorkflows {
+			if len(workflow.FailedJobs) == 0 {
+				continue
+			}
+			fmt.Fprintf(&sb, "\n* workflow `%s` has failed jobs", workflow.Workflow.Name)
+			describeFailedJobs(&sb, workflow)
+		}
+		for _, workflow := range summary.AllWorkflows {
+			grouped := matrixJobNames(workflow.MatrixJobs)
+			for _, job := range workflow.AllowedFailedJobs {
+				if !grouped[job.Name] {
+					fmt.Fprintf(&sb, "\n* job `%s` in workflow `%s` failed, but is allowed to fail", job.Name, workflow.Workflow.Name)
+				}
+			}
+			for _, matrixJob := range workflow.MatrixJobs {
+				if matrixJob.AllowedFailed > 0 && len(matrixJob.FailedVariants) == 0 {
+					fmt.Fprintf(&sb, "\n* %s in workflow `%s`, but is allowed to fail", matrixJob, workflow.Workflow.Name)
+				}
+			}
+		}
+	}
+
+	return title, sb.String()
+}
+
+// describeFailedJobs adds failed jobs of a workflow to a markdown summary, with one line per matrix job.
+func describeFailedJobs(sb *strings.Builder, workflow *WorkflowDetails) {
+	grouped := matrixJobNames(workflow.MatrixJobs)
+	for _, job := range workflow.FailedJobs {
+		if !grouped[job.Name] {
+			fmt.Fprintf(sb, "\n  * job `%s` failed", job.Name)
+		}
+	}
+	for _, matrixJob := range workflow.MatrixJobs {
+		if len(matrixJob.FailedVariants) > 0 {
+			fmt.Fprintf(sb, "\n  * %s", matrixJob)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+)
+
+func Test_GitHubRepositoryFromURL(t *testing.T) {
+	for _, test := range []struct {
+		url   string
+		owner string
+		repo  string
+		ok    bool
+	}{
+		{url: "https://github.com/influxdata/circleci-helper", owner: "influxdata", repo: "circleci-helper", ok: true},
+		{url: "https://github.com/influxdata/circleci-helper.git", owner: "influxdata", repo: "circleci-helper", ok: true},
+		{url: "https://github.com/influxdata", ok: false},
+		{url: "", ok: false},
+	} {
+		t.Run(test.url, func(tt *testing.T) {
+			owner, repo, ok := GitHubRepositoryFromURL(test.url)
+			if want, got := test.ok, ok; want != got {
+				tt.Fatalf("invalid result; want %v, got %v", want, got)
+			}
+			if want, got := test.owner, owner; want != got {
+				tt.Errorf("invalid owner; want %v, got %v", want, got)
+			}
+			if want, got := test.repo, repo; want != got {
+				tt.Errorf("invalid repo; want %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func Test_GitHubReporter(t *testing.T) {
+	var requests []string
+	var lastStatus gitHubCommitStatus
+	var lastCheckRun gitHubCheckRun
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if want, got := "Bearer token", r.Header.Get("Authorization"); want != got {
+			t.Errorf("invalid authorization header; want %v, got %v", want, got)
+		}
+		switch r.URL.Path {
+		case "/repos/influxdata/testproject/statuses/abc123":
+			json.NewDecoder(r.Body).Decode(&lastStatus)
+		default:
+			json.NewDecoder(r.Body).Decode(&lastCheckRun)
+			json.NewEncoder(w).Encode(&gitHubCheckRun{ID: 42})
+		}
+	}))
+	defer server.Close()
+
+	summary := &WorkflowsSummary{
+		Failed: true,
+		FailedWorkflows: []*WorkflowDetails{
+			{
+				Workflow:   &circle.Workflow{Name: "build", Status: "failed"},
+				FailedJobs: []*circle.Job{{Name: "test"}},
+			},
+		},
+	}
+
+	ctx := context.Background()
+	opts := GitHubOptions{
+		APIURL: server.URL + "/",
+		Token:  "token",
+		Owner:  "influxdata",
+		Repo:   "testproject",
+		Mode:   GitHubModeStatus,
+		Name:   "circleci-helper",
+	}
+
+	r, err := NewGitHubReporter(zap.NewNop(), opts, "abc123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Start(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := "pending", lastStatus.State; want != got {
+		t.Errorf("invalid state; want %v, got %v", want, got)
+	}
+	if err := r.Finish(ctx, OutcomeFailure, summary); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := "failure", lastStatus.State; want != got {
+		t.Errorf("invalid state; want %v, got %v", want, got)
+	}
+	if want, got := "circleci-helper", lastStatus.Context; want != got {
+		t.Errorf("invalid context; want %v, got %v", want, got)
+	}
+
+	opts.Mode = GitHubModeCheck
+	r, err = NewGitHubReporter(zap.NewNop(), opts, "abc123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Start(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Finish(ctx, OutcomeFailure, summary); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := "PATCH /repos/influxdata/testproject/check-runs/42", requests[len(requests)-1]; want != got {
+		t.Errorf("invalid request; want %v, got %v", want, got)
+	}
+	if want, got := "failure", lastCheckRun.Conclusion; want != got {
+		t.Errorf("invalid conclusion; want %v, got %v", want, got)
+	}
+	if lastCheckRun.Output == nil || lastCheckRun.Output.Summary == "" {
+		t.Errorf("check run summary was not sent")
+	}
+
+	if _, err := NewGitHubReporter(zap.NewNop(), GitHubOptions{Mode: "invalid"}, "abc123"); err == nil {
+		t.Errorf("expected an error for invalid mode")
+	}
+}
+
+func Test_describeOutcome_failedJobs(t *testing.T) {
+	// failed jobs are only retrieved if requested, which is done whenever outcome is reported to GitHub
+	m := newMockCircleClientWithData("failed", "success", "failed", "success")
+	result, err := WaitForJobs(context.Background(), zap.NewNop(), m, WaitForJobsOptions{
+		ProjectType:           "github",
+		Org:                   "influxdata",
+		Project:               "testproject",
+		PipelineNumber:        123,
+		GetFailedWorkflowJobs: true,
+		WaitDuration:          NewWaitForJobsDuration(time.Millisecond),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	title, summary := describeOutcome(OutcomeFailure, result)
+	if want, got := "One or more CircleCI workflows or jobs failed", title; want != got {
+		t.Errorf("invalid title; want %q, got %q", want, got)
+	}
+	for _, expected := range []string{"workflow `test-workflow-1` failed (status: failed)", "job `test-job-2-1` failed"} {
+		if !strings.Contains(summary, expected) {
+			t.Errorf("expected summary to contain %q, got %q", expected, summary)
+		}
+	}
+}
@@ -0,0 +1,474 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+)
+
+// JobLogsOptions allows passing options for retrieving output of one or more jobs.
+type JobLogsOptions struct {
+	ProjectType string
+	Org         string
+	Project     string
+	// JobNumber selects a single job by its number, instead of selecting jobs of a pipeline.
+	JobNumber        int
+	PipelineNumber   int
+	WorkflowNames    []string
+	IncludeWorkflows []string
+	ExcludeWorkflows []string
+	// JobNames, IncludeJobs and ExcludeJobs select jobs of the pipeline, all jobs are selected if none are set.
+	JobNames    []string
+	IncludeJobs []string
+	ExcludeJobs []string
+	// Steps are patterns of names of steps to retrieve output of, all steps if not set.
+	Steps []string
+	// Nodes limits output of jobs with parallelism to nodes with these indexes, if set.
+	Nodes []int
+	// FailedOnly only retrieves output of failed steps.
+	FailedOnly bool
+	// Tail limits output of each step to its last lines, if set.
+	Tail int
+	// Grep is a regular expression ; if set, only matching lines are written, along with ContextLines lines around them.
+	Grep         string
+	ContextLines int
+	// Concurrency is the number of jobs whose output is retrieved in parallel, DefaultConcurrency if not set.
+	Concurrency int
+}
+
+// JobLogsSummary describes output of jobs that was written.
+type JobLogsSummary struct {
+	Jobs    int `json:"jobs"`
+	Steps   int `json:"steps"`
+	Matches int `json:"matches"`
+}
+
+// logsJob is a job whose output is retrieved, along with its workflow if it was selected from a pipeline.
+type logsJob struct {
+	workflow *circle.Workflow
+	job      *circle.Job
+}
+
+// logsMaxBufferedBytes is how much output of a job is kept in memory while it waits for output of previous jobs to be
+// written ; output beyond it is spooled to a temporary file so that large logs retrieved in parallel do not exhaust memory.
+const logsMaxBufferedBytes = 1 << 20
+
+// logsJobOutput describes output of a single job that was written.
+type logsJobOutput struct {
+	steps   int
+	matches int
+}
+
+// WriteJobLogs writes output of selected jobs to w, with a header before output of each step ; jobs are written
+// in the order of their workflows and jobs regardless of the order their output was retrieved in. If Grep is set,
+// only steps with matching lines are written.
+func WriteJobLogs(ctx context.Context, logger *zap.Logger, client circle.Client, opts JobLogsOptions, w io.Writer) (*JobLogsSummary, error) {
+	sugar := logger.Sugar()
+
+	stepFilter, err := NewNameFilter(opts.Steps, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var grep *regexp.Regexp
+	if opts.Grep != "" {
+		grep, err = regexp.Compile(opts.Grep)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", opts.Grep, err)
+		}
+	}
+
+	jobs, err := getLogsJobs(ctx, client, opts)
+	if err != nil {
+		return nil, err
+	}
+	sugar.Infof("retrieving output of %d jobs", len(jobs))
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	summary := &JobLogsSummary{}
+	addOutput := func(output *logsJobOutput) {
+		if output.steps > 0 {
+			summary.Jobs++
+		}
+		summary.Steps += output.steps
+		summary.Matches += output.matches
+	}
+
+	// if jobs are retrieved one at a time, write their output as it is retrieved
+	if len(jobs) <= 1 || concurrency == 1 {
+		for _, job := range jobs {
+			output, err := getJobLogs(ctx, client, opts, job, stepFilter, grep, w)
+			if err != nil {
+				return nil, fmt.Errorf("unable to retrieve output of job %s: %w", describeLogsJob(job), err)
+			}
+			addOutput(output)
+		}
+		return summary, nil
+	}
+
+	// otherwise spool output of each job and write it once output of all previous jobs has been written
+	spools := make([]*logsSpool, len(jobs))
+	done := make([]chan struct{}, len(jobs))
+	for i := range jobs {
+		spools[i] = &logsSpool{}
+		done[i] = make(chan struct{})
+	}
+	defer func() {
+		for _, spool := range spools {
+			spool.close()
+		}
+	}()
+
+	outputs := make([]*logsJobOutput, len(jobs))
+	finished := make(chan struct{})
+	var retrieveErr error
+	go func() {
+		defer close(finished)
+		retrieveErr = forEachConcurrently(ctx, concurrency, len(jobs), func(ctx context.Context, i int) error {
+			defer close(done[i])
+			output, err := getJobLogs(ctx, client, opts, jobs[i], stepFilter, grep, spools[i])
+			if err != nil {
+				return fmt.Errorf("unable to retrieve output of job %s: %w", describeLogsJob(jobs[i]), err)
+			}
+			outputs[i] = output
+			return nil
+		})
+	}()
+
+	for i := range jobs {
+		// jobs after a failed one may never be started, so also stop waiting once retrieving has finished
+		select {
+		case <-done[i]:
+		case <-finished:
+		}
+		if outputs[i] == nil {
+			<-finished
+			return nil, retrieveErr
+		}
+		addOutput(outputs[i])
+		if err := spools[i].writeTo(w); err != nil {
+			// wait for jobs still being retrieved before their spools are removed
+			<-finished
+			return nil, err
+		}
+		spools[i].close()
+	}
+	<-finished
+
+	return summary, nil
+}
+
+// logsSpool keeps output of a job in memory up to logsMaxBufferedBytes, then writes it to a temporary file.
+type logsSpool struct {
+	buffer bytes.Buffer
+	file   *os.File
+}
+
+func (s *logsSpool) Write(p []byte) (int, error) {
+	if s.file == nil && s.buffer.Len()+len(p) > logsMaxBufferedBytes {
+		file, err := os.CreateTemp("", "circleci-helper-logs-*")
+		if err != nil {
+			return 0, err
+		}
+		s.file = file
+		if _, err := s.buffer.WriteTo(file); err != nil {
+			return 0, err
+		}
+	}
+	if s.file != nil {
+		return s.file.Write(p)
+	}
+	return s.buffer.Write(p)
+}
+
+// writeTo writes all output written to the spool to w.
+func (s *logsSpool) writeTo(w io.Writer) error {
+	if s.file == nil {
+		_, err := s.buffer.WriteTo(w)
+		return err
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := io.Copy(w, s.file)
+	return err
+}
+
+// close releases memory and removes the temporary file used by the spool, if any.
+func (s *logsSpool) close() {
+	s.buffer.Reset()
+	if s.file != nil {
+		s.file.Close()
+		os.Remove(s.file.Name())
+		s.file = nil
+	}
+}
+
+// getLogsJobs returns jobs selected by options.
+func getLogsJobs(ctx context.Context, client circle.Client, opts JobLogsOptions) ([]*logsJob, error) {
+	if opts.JobNumber != 0 {
+		return []*logsJob{{job: &circle.Job{JobNumber: opts.JobNumber}}}, nil
+	}
+
+	workflowFilter, err := newWorkflowFilter(opts.WorkflowNames, opts.IncludeWorkflows, opts.ExcludeWorkflows)
+	if err != nil {
+		return nil, err
+	}
+
+	jobFilter, err := newJobFilter(nil, nil, opts.IncludeJobs, opts.ExcludeJobs)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range opts.JobNames {
+		jobFilter.Include = append(jobFilter.Include, exactNamePattern(name))
+	}
+
+	pipelineID, err := client.GetPipelineID(ctx, opts.ProjectType, opts.Org, opts.Project, opts.PipelineNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := checkWorkflowsStatus(
+		ctx, client, pipelineID,
+		checkWorkflowStatusOpts{
+			filterWorkflow:      filterWorkflowByName(workflowFilter, nil),
+			filterJob:           filterJobByName(jobFilter, nil),
+			succeededJobDetails: !opts.FailedOnly,
+			failedJobDetails:    true,
+			pendingJobDetails:   true,
+			concurrency:         opts.Concurrency,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*logsJob
+	for _, workflow := range status.AllWorkflows {
+		var jobs []*circle.Job
+		jobs = append(jobs, workflow.FailedJobs...)
+		jobs = append(jobs, workflow.AllowedFailedJobs...)
+		jobs = append(jobs, workflow.PendingJobs...)
+		if !opts.FailedOnly {
+			jobs = append(jobs, workflow.SucceededJobs...)
+		}
+
+		for _, job := range jobs {
+			// jobs that have not started yet, and approval jobs, do not have any output
+			if job.JobNumber == 0 || job.Status == "blocked" {
+				continue
+			}
+			result = append(result, &logsJob{workflow: workflow.Workflow, job: job})
+		}
+	}
+
+	return result, nil
+}
+
+// describeLogsJob returns a human-friendly description of a job.
+func describeLogsJob(job *logsJob) string {
+	if job.workflow == nil {
+		return fmt.Sprintf("%d", job.job.JobNumber)
+	}
+	return fmt.Sprintf("%s in workflow %s", job.job.Name, job.workflow.Name)
+}
+
+// getJobLogs writes output of steps of a job that match criteria to w.
+func getJobLogs(ctx context.Context, client circle.Client, opts JobLogsOptions, job *logsJob, stepFilter *NameFilter, grep *regexp.Regexp, w io.Writer) (*logsJobOutput, error) {
+	output := &logsJobOutput{}
+
+	details, err := client.GetJobDetails(ctx, opts.ProjectType, opts.Org, opts.Project, job.job.JobNumber)
+	if err != nil {
+		// check if the error was 404 - if so, assume the job has not yet been run
+		httpErr, ok := err.(*circle.ClientHTTPError)
+		if ok && httpErr.StatusCode == 404 && job.workflow != nil {
+			return output, nil
+		}
+		return nil, err
+	}
+
+	nodes := map[int]bool{}
+	for _, node := range opts.Nodes {
+		nodes[node] = true
+	}
+
+	limits := OutputLimits{}
+	if opts.Tail > 0 {
+		limits = OutputLimits{MaxLines: opts.Tail, TailOnly: true}
+	}
+
+	for _, step := range details.Steps {
+		if !stepFilter.Match(step.Name) {
+			continue
+		}
+		for _, action := range step.Actions {
+			if (opts.FailedOnly && !action.Failed) || (len(nodes) > 0 && !nodes[action.Index]) {
+				continue
+			}
+
+			header := fmt.Sprintf("==> job %s step %s", describeLogsJob(job), step.Name)
+			if action.Parallel {
+				header += fmt.Sprintf(" node %d", action.Index)
+			}
+			if action.Failed {
+				header += " (failed)"
+			}
+
+			if grep == nil {
+				fmt.Fprintf(w, "%s\n", header)
+				lw := &lastByteWriter{w: w}
+				if _, err := WriteJobActionOutput(ctx, client, &action, lw, limits); err != nil {
+					return nil, err
+				}
+				if err := lw.ensureTrailingNewline(); err != nil {
+					return nil, err
+				}
+				output.steps++
+				continue
+			}
+
+			// only write the header once the first matching line is found
+			hw := &headerWriter{w: w, header: header + "\n"}
+			gw := newGrepWriter(hw, grep, opts.ContextLines)
+			if _, err := WriteJobActionOutput(ctx, client, &action, gw, limits); err != nil {
+				return nil, err
+			}
+			gw.Flush()
+			if gw.matches > 0 {
+				output.steps++
+				output.matches += gw.matches
+			}
+		}
+	}
+
+	return output, nil
+}
+
+// lastByteWriter writes to w, keeping track of the last byte written.
+type lastByteWriter struct {
+	w    io.Writer
+	last []byte
+}
+
+func (l *lastByteWriter) Write(p []byte) (int, error) {
+	n, err := l.w.Write(p)
+	if n > 0 {
+		l.last = []byte{p[n-1]}
+	}
+	return n, err
+}
+
+// ensureTrailingNewline writes a newline unless nothing was written or output already ends with one.
+func (l *lastByteWriter) ensureTrailingNewline() error {
+	if len(l.last) == 0 || l.last[0] == '\n' {
+		return nil
+	}
+	_, err := l.w.Write([]byte{'\n'})
+	return err
+}
+
+// headerWriter writes header to w before the first write.
+type headerWriter struct {
+	w       io.Writer
+	header  string
+	written bool
+}
+
+func (h *headerWriter) Write(p []byte) (int, error) {
+	if !h.written && len(p) > 0 {
+		h.written = true
+		if _, err := io.WriteString(h.w, h.header); err != nil {
+			return 0, err
+		}
+	}
+	return h.w.Write(p)
+}
+
+// grepWriter writes lines matching a regular expression along with lines around them, prefixed with their line numbers
+// similar to grep: "12:" for matching lines, "11-" for context lines and "--" between non-adjacent groups of lines.
+type grepWriter struct {
+	w            io.Writer
+	pattern      *regexp.Regexp
+	contextLines int
+	partial      strings.Builder
+	// before holds the most recent lines that were not written, for context before matching lines
+	before      []string
+	lineNumber  int
+	lastWritten int
+	after       int
+	matches     int
+}
+
+func newGrepWriter(w io.Writer, pattern *regexp.Regexp, contextLines int) *grepWriter {
+	return &grepWriter{w: w, pattern: pattern, contextLines: contextLines}
+}
+
+// Write processes all complete lines of output.
+func (g *grepWriter) Write(p []byte) (int, error) {
+	data := string(p)
+	for {
+		newline := strings.IndexByte(data, '\n')
+		if newline < 0 {
+			g.partial.WriteString(data)
+			break
+		}
+		g.partial.WriteString(data[:newline])
+		g.addLine(g.partial.String())
+		g.partial.Reset()
+		data = data[newline+1:]
+	}
+	return len(p), nil
+}
+
+// Flush processes the last line if it did not end with a newline.
+func (g *grepWriter) Flush() {
+	if g.partial.Len() > 0 {
+		g.addLine(g.partial.String())
+		g.partial.Reset()
+	}
+}
+
+func (g *grepWriter) addLine(line string) {
+	line = strings.TrimRight(StripANSI(line), "\r")
+	g.lineNumber++
+
+	if g.pattern.MatchString(line) {
+		if g.lastWritten > 0 && g.lineNumber-len(g.before) > g.lastWritten+1 {
+			fmt.Fprintf(g.w, "--\n")
+		}
+		for i, context := range g.before {
+			fmt.Fprintf(g.w, "%d-%s\n", g.lineNumber-len(g.before)+i, context)
+		}
+		g.before = g.before[:0]
+		fmt.Fprintf(g.w, "%d:%s\n", g.lineNumber, line)
+		g.lastWritten = g.lineNumber
+		g.after = g.contextLines
+		g.matches++
+		return
+	}
+
+	if g.after > 0 {
+		fmt.Fprintf(g.w, "%d-%s\n", g.lineNumber, line)
+		g.lastWritten = g.lineNumber
+		g.after--
+		return
+	}
+
+	if g.contextLines > 0 {
+		g.before = append(g.before, line)
+		if len(g.before) > g.contextLines {
+			g.before = g.before[1:]
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+)
+
+func newMockCircleClientWithLogs() *mockCircleClient {
+	m := newMockCircleClientWithParallelJob()
+	for number := 2; number <= 4; number++ {
+		m.jobDetailsMap[number] = &circle.JobDetails{}
+	}
+	m.jobDetailsMap[3] = &circle.JobDetails{
+		Steps: []circle.JobStep{
+			{Name: "lint", Actions: []circle.JobAction{
+				{Name: "lint", Status: "failed", Failed: true, OutputURL: "output-lint", HasOutput: true},
+			}},
+		},
+	}
+	m.jobOutputMap["output-0-1"] = []circle.JobOutputMessage{{Message: "checkout 0\n"}}
+	m.jobOutputMap["output-1-1"] = []circle.JobOutputMessage{{Message: "checkout 1\n"}}
+	m.jobOutputMap["output-2-1"] = []circle.JobOutputMessage{{Message: "checkout 2\n"}}
+	m.jobOutputMap["output-1-2"] = []circle.JobOutputMessage{{Message: "ok node 1\n"}}
+	m.jobOutputMap["output-lint"] = []circle.JobOutputMessage{{Message: "a.go:1: FAIL unused variable\nb.go:2: ok\n"}}
+	return m
+}
+
+func Test_WriteJobLogs(t *testing.T) {
+	m := newMockCircleClientWithLogs()
+
+	for _, test := range []struct {
+		name            string
+		opts            JobLogsOptions
+		expectedOutput  string
+		expectedSteps   int
+		expectedMatches int
+	}{
+		{
+			name: "job number, failed steps on a node",
+			opts: JobLogsOptions{JobNumber: 1, FailedOnly: true, Nodes: []int{0}},
+			expectedOutput: "==> job 1 step unit tests node 0 (failed)\n" +
+				"FAIL node 0\n",
+			expectedSteps: 1,
+		},
+		{
+			name: "job name and step",
+			opts: JobLogsOptions{PipelineNumber: 123, JobNames: []string{"test-linux"}, Steps: []string{"checkout"}, Nodes: []int{1}},
+			expectedOutput: "==> job test-linux in workflow test step checkout node 1\n" +
+				"checkout 1\n",
+			expectedSteps: 1,
+		},
+		{
+			name: "search all jobs",
+			opts: JobLogsOptions{PipelineNumber: 123, Grep: "FAIL", Concurrency: 2},
+			expectedOutput: "==> job test-linux in workflow test step unit tests node 0 (failed)\n" +
+				"1:FAIL node 0\n" +
+				"==> job lint in workflow test step lint (failed)\n" +
+				"1:a.go:1: FAIL unused variable\n",
+			expectedSteps:   2,
+			expectedMatches: 2,
+		},
+		{
+			name: "search all jobs one at a time",
+			opts: JobLogsOptions{PipelineNumber: 123, Grep: "FAIL", Concurrency: 1},
+			expectedOutput: "==> job test-linux in workflow test step unit tests node 0 (failed)\n" +
+				"1:FAIL node 0\n" +
+				"==> job lint in workflow test step lint (failed)\n" +
+				"1:a.go:1: FAIL unused variable\n",
+			expectedSteps:   2,
+			expectedMatches: 2,
+		},
+		{
+			name:           "no matches",
+			opts:           JobLogsOptions{PipelineNumber: 123, Grep: "panic"},
+			expectedOutput: "",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			opts := test.opts
+			opts.ProjectType, opts.Org, opts.Project = "github", "influxdata", "testproject"
+
+			var sb strings.Builder
+			summary, err := WriteJobLogs(context.Background(), zap.NewNop(), m, opts, &sb)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want, got := test.expectedOutput, sb.String(); want != got {
+				t.Errorf("invalid output; want %q, got %q", want, got)
+			}
+			if want, got := test.expectedSteps, summary.Steps; want != got {
+				t.Errorf("invalid number of steps; want %v, got %v", want, got)
+			}
+			if want, got := test.expectedMatches, summary.Matches; want != got {
+				t.Errorf("invalid number of matches; want %v, got %v", want, got)
+			}
+		})
+	}
+
+	// invalid regular expressions are reported
+	if _, err := WriteJobLogs(context.Background(), zap.NewNop(), m, JobLogsOptions{
+		ProjectType: "github", Org: "influxdata", Project: "testproject", JobNumber: 1, Grep: "(",
+	}, &strings.Builder{}); err == nil {
+		t.Errorf("expected error for invalid regular expression")
+	}
+
+	// errors retrieving output of any job are reported
+	delete(m.jobDetailsMap, 3)
+	if _, err := WriteJobLogs(context.Background(), zap.NewNop(), m, JobLogsOptions{
+		ProjectType: "github", Org: "influxdata", Project: "testproject", PipelineNumber: 123, Concurrency: 2,
+	}, &strings.Builder{}); err == nil {
+		t.Errorf("expected error for job whose details could not be retrieved")
+	}
+}
+
+func Test_logsSpool(t *testing.T) {
+	line := strings.Repeat("x", 1023) + "\n"
+	for _, lines := range []int{1, 2 * logsMaxBufferedBytes / len(line)} {
+		spool := &logsSpool{}
+		for i := 0; i < lines; i++ {
+			if _, err := spool.Write([]byte(line)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if want, got := lines > 1, spool.file != nil; want != got {
+			t.Errorf("invalid spooling to a file for %d lines; want %v, got %v", lines, want, got)
+		}
+
+		var sb strings.Builder
+		if err := spool.writeTo(&sb); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want, got := strings.Repeat(line, lines), sb.String(); want != got {
+			t.Errorf("invalid output for %d lines; want %d bytes, got %d bytes", lines, len(want), len(got))
+		}
+
+		name := ""
+		if spool.file != nil {
+			name = spool.file.Name()
+		}
+		spool.close()
+		if name != "" {
+			if _, err := os.Stat(name); !os.IsNotExist(err) {
+				t.Errorf("expected temporary file %s to be removed, got %v", name, err)
+			}
+		}
+	}
+}
+
+func Test_grepWriter(t *testing.T) {
+	var sb strings.Builder
+	w := newGrepWriter(&sb, regexp.MustCompile("match"), 1)
+	for _, chunk := range []string{"1\n2\nmat", "ch 3\n4\n5\n6\n", "match 7\n\x1b[31mmatch\x1b[0m 8\n9\n10"} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	w.Flush()
+
+	expected := "2-2\n3:match 3\n4-4\n--\n6-6\n7:match 7\n8:match 8\n9-9\n"
+	if want, got := expected, sb.String(); want != got {
+		t.Errorf("invalid output; want %q, got %q", want, got)
+	}
+	if want, got := 3, w.matches; want != got {
+		t.Errorf("invalid number of matches; want %v, got %v", want, got)
+	}
+}
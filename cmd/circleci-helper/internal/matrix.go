@@ -0,0 +1,245 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+)
+
+// matrixParameterRegexp matches matrix parameters in job name patterns, such as << matrix.go >>.
+var matrixParameterRegexp = regexp.MustCompile(`<<\s*matrix\.[\w-]+\s*>>`)
+
+// MatrixJob summarizes jobs of a workflow that were expanded from a single matrix job.
+type MatrixJob struct {
+	Name          string   `json:"name"`
+	Jobs          []string `json:"jobs"`
+	Succeeded     int      `json:"succeeded"`
+	Failed        int      `json:"failed"`
+	AllowedFailed int      `json:"allowed_failed,omitempty"`
+	Pending       int      `json:"pending"`
+	// FailedVariants lists names of jobs that have failed and are not allowed to fail.
+	FailedVariants []string `json:"failed_variants,omitempty"`
+}
+
+// String returns a one line summary of the matrix job.
+func (m *MatrixJob) String() string {
+	summary := fmt.Sprintf("matrix job %s: %d succeeded, %d failed, %d pending", m.Name, m.Succeeded, m.Failed, m.Pending)
+	if m.AllowedFailed > 0 {
+		summary += fmt.Sprintf(", %d failed but allowed to fail", m.AllowedFailed)
+	}
+	if len(m.FailedVariants) > 0 {
+		summary += fmt.Sprintf(" (failed: %s)", strings.Join(m.FailedVariants, ", "))
+	}
+	return summary
+}
+
+// matrixPattern is a user supplied pattern of a matrix job, such as test-<< matrix.go >>-<< matrix.os >>.
+type matrixPattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// MatrixGrouper groups jobs expanded from matrix jobs, either by user supplied patterns or by inferring
+// matrix parameters from job names.
+type MatrixGrouper struct {
+	patterns []*matrixPattern
+	infer    bool
+}
+
+// NewMatrixGrouper creates a MatrixGrouper from patterns, where each << matrix.name >> parameter or * matches any text.
+// If infer is true, jobs not matching any of the patterns are grouped if their names only differ in dash separated parts,
+// which is how CircleCI names matrix jobs by default.
+func NewMatrixGrouper(patterns []string, infer bool) (*MatrixGrouper, error) {
+	g := &MatrixGrouper{infer: infer}
+	for _, pattern := range patterns {
+		p, err := parseMatrixPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		g.patterns = append(g.patterns, p)
+	}
+	return g, nil
+}
+
+// parseMatrixPattern converts a matrix job pattern to a regular expression.
+func parseMatrixPattern(pattern string) (*matrixPattern, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("matrix job pattern cannot be empty")
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	quoteLiteral := func(literal string) {
+		expr.WriteString(strings.ReplaceAll(regexp.QuoteMeta(literal), `\*`, ".*"))
+	}
+	last := 0
+	for _, loc := range matrixParameterRegexp.FindAllStringIndex(pattern, -1) {
+		quoteLiteral(pattern[last:loc[0]])
+		expr.WriteString("(.+)")
+		last = loc[1]
+	}
+	quoteLiteral(pattern[last:])
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid matrix job pattern %q: %w", pattern, err)
+	}
+	return &matrixPattern{name: pattern, re: re}, nil
+}
+
+// GroupJobs returns matrix jobs for all jobs of a workflow, in the order the jobs were first reported ;
+// jobs that are not part of any matrix job are not returned.
+func (g *MatrixGrouper) GroupJobs(details *WorkflowDetails) []*MatrixJob {
+	if g == nil {
+		return nil
+	}
+
+	var result []*MatrixJob
+	groups := map[string]*MatrixJob{}
+	groupNames := g.groupNames(details)
+
+	add := func(jobs []*circle.Job, update func(m *MatrixJob, job *circle.Job)) {
+		for _, job := range jobs {
+			name, ok := groupNames[job.Name]
+			if !ok {
+				continue
+			}
+			m := groups[name]
+			if m == nil {
+				m = &MatrixJob{Name: name}
+				groups[name] = m
+				result = append(result, m)
+			}
+			m.Jobs = append(m.Jobs, job.Name)
+			update(m, job)
+		}
+	}
+
+	add(details.SucceededJobs, func(m *MatrixJob, job *circle.Job) { m.Succeeded++ })
+	add(details.PendingJobs, func(m *MatrixJob, job *circle.Job) { m.Pending++ })
+	add(details.FailedJobs, func(m *MatrixJob, job *circle.Job) {
+		m.Failed++
+		m.FailedVariants = append(m.FailedVariants, job.Name)
+	})
+	add(details.AllowedFailedJobs, func(m *MatrixJob, job *circle.Job) {
+		m.Failed++
+		m.AllowedFailed++
+	})
+
+	return result
+}
+
+// groupNames maps names of jobs that are part of a matrix job to name of the matrix job.
+func (g *MatrixGrouper) groupNames(details *WorkflowDetails) map[string]string {
+	result := map[string]string{}
+
+	var unmatched []string
+	for _, jobs := range [][]*circle.Job{details.SucceededJobs, details.PendingJobs, details.FailedJobs, details.AllowedFailedJobs} {
+		for _, job := range jobs {
+			matched := false
+			for _, p := range g.patterns {
+				if p.re.MatchString(job.Name) {
+					result[job.Name] = p.name
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				unmatched = append(unmatched, job.Name)
+			}
+		}
+	}
+
+	if g.infer {
+		for name, group := range inferMatrixJobs(unmatched) {
+			result[name] = group
+		}
+	}
+
+	return result
+}
+
+// inferMatrixJobs groups job names that have the same first dash separated part and the same number of parts,
+// naming each group by replacing parts that differ with *, such as test-*-* for test-1.22-linux and test-1.23-darwin.
+// As unrelated jobs such as build-linux and build-docs also share their first part, jobs are only grouped if at least
+// two parts differ and the jobs cover every combination of their values, as jobs expanded from a matrix do ; matrix
+// jobs with a single parameter need to be specified explicitly.
+func inferMatrixJobs(names []string) map[string]string {
+	candidates := map[string][][]string{}
+	var keys []string
+	for _, name := range names {
+		parts := strings.Split(name, "-")
+		if len(parts) < 2 {
+			continue
+		}
+		key := fmt.Sprintf("%s/%d", parts[0], len(parts))
+		if _, ok := candidates[key]; !ok {
+			keys = append(keys, key)
+		}
+		candidates[key] = append(candidates[key], parts)
+	}
+
+	result := map[string]string{}
+	for _, key := range keys {
+		jobs := candidates[key]
+		if len(jobs) < 2 {
+			continue
+		}
+
+		groupParts := append([]string{}, jobs[0]...)
+		values := make([]map[string]bool, len(groupParts))
+		for i := range values {
+			values[i] = map[string]bool{}
+		}
+		for _, parts := range jobs {
+			for i := range parts {
+				values[i][parts[i]] = true
+				if parts[i] != groupParts[i] {
+					groupParts[i] = "*"
+				}
+			}
+		}
+
+		// check that values of differing parts vary independently of each other, covering all combinations
+		varying, combinations := 0, 1
+		for _, v := range values {
+			if len(v) > 1 {
+				varying++
+				combinations *= len(v)
+			}
+		}
+		if varying < 2 || combinations != len(uniqueJobNames(jobs)) {
+			continue
+		}
+		group := strings.Join(groupParts, "-")
+
+		for _, parts := range jobs {
+			result[strings.Join(parts, "-")] = group
+		}
+	}
+
+	return result
+}
+
+// uniqueJobNames returns distinct job names from their dash separated parts.
+func uniqueJobNames(jobs [][]string) map[string]bool {
+	result := map[string]bool{}
+	for _, parts := range jobs {
+		result[strings.Join(parts, "-")] = true
+	}
+	return result
+}
+
+// matrixJobNames returns names of all jobs that are part of one of matrix jobs.
+func matrixJobNames(matrixJobs []*MatrixJob) map[string]bool {
+	result := map[string]bool{}
+	for _, m := range matrixJobs {
+		for _, name := range m.Jobs {
+			result[name] = true
+		}
+	}
+	return result
+}
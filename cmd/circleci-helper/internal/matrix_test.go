@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+)
+
+func newMatrixTestWorkflowDetails() *WorkflowDetails {
+	return &WorkflowDetails{
+		Workflow: &circle.Workflow{ID: "1", Name: "build", Status: "running"},
+		SucceededJobs: []*circle.Job{
+			{Name: "lint", Status: "success"},
+			{Name: "test-1.22-linux", Status: "success"},
+			{Name: "test-1.23-linux", Status: "success"},
+		},
+		PendingJobs: []*circle.Job{
+			{Name: "test-1.23-darwin", Status: "running"},
+			{Name: "deploy", Status: "blocked"},
+		},
+		FailedJobs: []*circle.Job{
+			{Name: "test-1.22-darwin", Status: "failed"},
+		},
+	}
+}
+
+func Test_MatrixGrouper_patterns(t *testing.T) {
+	g, err := NewMatrixGrouper([]string{"test-<< matrix.go >>-<< matrix.os >>"}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := g.GroupJobs(newMatrixTestWorkflowDetails())
+	want := []*MatrixJob{
+		{
+			Name:           "test-<< matrix.go >>-<< matrix.os >>",
+			Jobs:           []string{"test-1.22-linux", "test-1.23-linux", "test-1.23-darwin", "test-1.22-darwin"},
+			Succeeded:      2,
+			Failed:         1,
+			Pending:        1,
+			FailedVariants: []string{"test-1.22-darwin"},
+		},
+	}
+	if !reflect.DeepEqual(want, result) {
+		t.Errorf("invalid matrix jobs; want %+v, got %+v", want[0], result)
+	}
+
+	if want, got := "matrix job test-<< matrix.go >>-<< matrix.os >>: 2 succeeded, 1 failed, 1 pending (failed: test-1.22-darwin)", result[0].String(); want != got {
+		t.Errorf("invalid summary; want %q, got %q", want, got)
+	}
+}
+
+func Test_MatrixGrouper_glob(t *testing.T) {
+	g, err := NewMatrixGrouper([]string{"test-*-linux"}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := g.GroupJobs(newMatrixTestWorkflowDetails())
+	if want, got := 1, len(result); want != got {
+		t.Fatalf("invalid number of matrix jobs; want %v, got %v", want, got)
+	}
+	if want, got := []string{"test-1.22-linux", "test-1.23-linux"}, result[0].Jobs; !reflect.DeepEqual(want, got) {
+		t.Errorf("invalid jobs; want %v, got %v", want, got)
+	}
+}
+
+func Test_MatrixGrouper_infer(t *testing.T) {
+	g, err := NewMatrixGrouper(nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := g.GroupJobs(newMatrixTestWorkflowDetails())
+	if want, got := 1, len(result); want != got {
+		t.Fatalf("invalid number of matrix jobs; want %v, got %v", want, got)
+	}
+	if want, got := "test-*-*", result[0].Name; want != got {
+		t.Errorf("invalid matrix job name; want %q, got %q", want, got)
+	}
+	if want, got := 4, len(result[0].Jobs); want != got {
+		t.Errorf("invalid number of jobs; want %v, got %v", want, got)
+	}
+}
+
+func Test_inferMatrixJobs(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		names    []string
+		expected map[string]string
+	}{
+		{
+			name:  "all combinations",
+			names: []string{"test-1.22-linux", "test-1.23-linux", "test-1.22-darwin", "test-1.23-darwin", "lint"},
+			expected: map[string]string{
+				"test-1.22-linux": "test-*-*", "test-1.23-linux": "test-*-*", "test-1.22-darwin": "test-*-*", "test-1.23-darwin": "test-*-*",
+			},
+		},
+		{
+			name:     "unrelated jobs with the same first part",
+			names:    []string{"build-linux", "build-docs"},
+			expected: map[string]string{},
+		},
+		{
+			name:     "unrelated jobs differing in multiple parts",
+			names:    []string{"build-linux-amd64", "build-docs-html"},
+			expected: map[string]string{},
+		},
+		{
+			name:     "missing combinations",
+			names:    []string{"test-1.22-linux", "test-1.23-linux", "test-1.22-darwin"},
+			expected: map[string]string{},
+		},
+	} {
+		t.Run(test.name, func(tt *testing.T) {
+			if got := inferMatrixJobs(test.names); !reflect.DeepEqual(test.expected, got) {
+				tt.Errorf("invalid matrix jobs; want %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func Test_MatrixGrouper_nil(t *testing.T) {
+	var g *MatrixGrouper
+	if result := g.GroupJobs(newMatrixTestWorkflowDetails()); result != nil {
+		t.Errorf("expected no matrix jobs, got %v", result)
+	}
+}
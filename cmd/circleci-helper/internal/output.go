@@ -0,0 +1,171 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+)
+
+// OutputLimits limits how much output of an action is retrieved ; zero values mean no limit.
+type OutputLimits struct {
+	// MaxBytes is the maximum number of bytes of output.
+	MaxBytes int64
+	// MaxLines is the maximum number of lines of output.
+	MaxLines int
+	// TailOnly keeps the last bytes and lines of output instead of the first ones ; whole output is still retrieved,
+	// but only the last MaxBytes and MaxLines are kept in memory. Only whole lines are kept, unless the last line
+	// alone exceeds MaxBytes.
+	TailOnly bool
+}
+
+// OutputSummary describes output of an action that was written.
+type OutputSummary struct {
+	// Bytes is the number of bytes of output written.
+	Bytes int64 `json:"bytes"`
+	// Lines is the number of lines of output written, including the last line if it does not end with a newline.
+	Lines int `json:"lines"`
+	// Truncated is set if some of the output was not written because of limits.
+	Truncated bool `json:"truncated"`
+}
+
+// WriteJobActionOutput retrieves output of an action and writes it to w as it is being retrieved, up to the limits.
+// Actions without output do not write anything.
+func WriteJobActionOutput(ctx context.Context, client circle.Client, action *circle.JobAction, w io.Writer, limits OutputLimits) (*OutputSummary, error) {
+	if limits.TailOnly && (limits.MaxBytes > 0 || limits.MaxLines > 0) {
+		tail := &outputTail{limits: limits}
+		if err := client.StreamJobActionOutput(ctx, action, func(message circle.JobOutputMessage) error {
+			tail.add(message.Message)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		return tail.writeTo(w)
+	}
+
+	head := &outputHead{w: w, limits: limits, summary: &OutputSummary{}}
+	if err := client.StreamJobActionOutput(ctx, action, func(message circle.JobOutputMessage) error {
+		return head.write(message.Message)
+	}); err != nil {
+		return nil, err
+	}
+	return head.summary, nil
+}
+
+// outputHead writes the first lines and bytes of output, up to the limits.
+type outputHead struct {
+	w       io.Writer
+	limits  OutputLimits
+	summary *OutputSummary
+	// partial is set if the last line written did not end with a newline
+	partial bool
+}
+
+// write writes text, returning circle.ErrStopOutput once the limits were reached.
+func (h *outputHead) write(text string) error {
+	for text != "" {
+		if h.limits.MaxLines > 0 && h.summary.Lines >= h.limits.MaxLines && !h.partial {
+			h.summary.Truncated = true
+			return circle.ErrStopOutput
+		}
+
+		// write output one line at a time, so that the number of lines can be limited
+		line := text
+		if newline := strings.IndexByte(text, '\n'); newline >= 0 {
+			line = text[:newline+1]
+		}
+
+		if h.limits.MaxBytes > 0 && h.summary.Bytes+int64(len(line)) > h.limits.MaxBytes {
+			line = line[:h.limits.MaxBytes-h.summary.Bytes]
+			h.summary.Truncated = true
+		}
+
+		if line != "" {
+			n, err := io.WriteString(h.w, line)
+			h.summary.Bytes += int64(n)
+			if err != nil {
+				return err
+			}
+			if !h.partial {
+				h.summary.Lines++
+			}
+			h.partial = !strings.HasSuffix(line, "\n")
+		}
+
+		if h.summary.Truncated {
+			return circle.ErrStopOutput
+		}
+		text = text[len(line):]
+	}
+	return nil
+}
+
+// outputTail keeps the last lines and bytes of output, up to the limits.
+type outputTail struct {
+	limits OutputLimits
+	// lines holds kept lines starting at index start, dropped lines are only removed once in a while to avoid copying
+	lines     []string
+	start     int
+	bytes     int64
+	truncated bool
+}
+
+// add adds text to the output, dropping the oldest lines once the limits were reached.
+func (t *outputTail) add(text string) {
+	for text != "" {
+		line := text
+		if newline := strings.IndexByte(text, '\n'); newline >= 0 {
+			line = text[:newline+1]
+		}
+		text = text[len(line):]
+
+		// continue the last line if it did not end with a newline
+		if last := len(t.lines) - 1; last >= t.start && !strings.HasSuffix(t.lines[last], "\n") {
+			t.lines[last] += line
+		} else {
+			t.lines = append(t.lines, line)
+		}
+		t.bytes += int64(len(line))
+
+		t.trim()
+	}
+}
+
+// trim drops the oldest lines, or the beginning of the only line, until output is within the limits.
+func (t *outputTail) trim() {
+	last := len(t.lines) - 1
+	for t.start < last &&
+		((t.limits.MaxLines > 0 && len(t.lines)-t.start > t.limits.MaxLines) ||
+			(t.limits.MaxBytes > 0 && t.bytes > t.limits.MaxBytes)) {
+		t.bytes -= int64(len(t.lines[t.start]))
+		t.lines[t.start] = ""
+		t.start++
+		t.truncated = true
+	}
+
+	if t.limits.MaxBytes > 0 && t.bytes > t.limits.MaxBytes {
+		t.lines[last] = t.lines[last][t.bytes-t.limits.MaxBytes:]
+		t.bytes = t.limits.MaxBytes
+		t.truncated = true
+	}
+
+	if t.start > len(t.lines)/2 {
+		t.lines = append(t.lines[:0], t.lines[t.start:]...)
+		t.start = 0
+	}
+}
+
+// writeTo writes the kept output to w.
+func (t *outputTail) writeTo(w io.Writer) (*OutputSummary, error) {
+	summary := &OutputSummary{Truncated: t.truncated}
+	for _, line := range t.lines[t.start:] {
+		n, err := io.WriteString(w, line)
+		summary.Bytes += int64(n)
+		if err != nil {
+			return nil, err
+		}
+		summary.Lines++
+	}
+	return summary, nil
+}
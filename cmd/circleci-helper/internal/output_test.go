@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+)
+
+func Test_WriteJobActionOutput(t *testing.T) {
+	m := newMockCircleClient("github", "influxdata", "testproject")
+	// messages do not necessarily end at line boundaries
+	m.jobOutputMap["output"] = []circle.JobOutputMessage{
+		{Message: "line 1\nline"},
+		{Message: " 2\nline 3\n"},
+		{Message: "line 4\nline 5"},
+	}
+	action := &circle.JobAction{Name: "test", OutputURL: "output", HasOutput: true}
+
+	for _, test := range []struct {
+		name              string
+		action            *circle.JobAction
+		limits            OutputLimits
+		expectedOutput    string
+		expectedLines     int
+		expectedTruncated bool
+	}{
+		{
+			name:           "no limits",
+			action:         action,
+			expectedOutput: "line 1\nline 2\nline 3\nline 4\nline 5",
+			expectedLines:  5,
+		},
+		{
+			name:              "first lines",
+			action:            action,
+			limits:            OutputLimits{MaxLines: 2},
+			expectedOutput:    "line 1\nline 2\n",
+			expectedLines:     2,
+			expectedTruncated: true,
+		},
+		{
+			name:              "first bytes",
+			action:            action,
+			limits:            OutputLimits{MaxBytes: 10},
+			expectedOutput:    "line 1\nlin",
+			expectedLines:     2,
+			expectedTruncated: true,
+		},
+		{
+			name:              "last lines",
+			action:            action,
+			limits:            OutputLimits{MaxLines: 2, TailOnly: true},
+			expectedOutput:    "line 4\nline 5",
+			expectedLines:     2,
+			expectedTruncated: true,
+		},
+		{
+			name:              "last bytes",
+			action:            action,
+			limits:            OutputLimits{MaxBytes: 10, TailOnly: true},
+			expectedOutput:    "line 5",
+			expectedLines:     1,
+			expectedTruncated: true,
+		},
+		{
+			name:           "limits not reached",
+			action:         action,
+			limits:         OutputLimits{MaxLines: 5, MaxBytes: 100, TailOnly: true},
+			expectedOutput: "line 1\nline 2\nline 3\nline 4\nline 5",
+			expectedLines:  5,
+		},
+		{
+			name:   "action without output",
+			action: &circle.JobAction{Name: "test", OutputURL: "output", HasOutput: false},
+		},
+		{
+			name:   "action without output URL",
+			action: &circle.JobAction{Name: "test", HasOutput: true},
+		},
+	} {
+		t.Run(test.name, func(tt *testing.T) {
+			var sb strings.Builder
+			summary, err := WriteJobActionOutput(context.Background(), m, test.action, &sb, test.limits)
+			if err != nil {
+				tt.Fatalf("unexpected error: %v", err)
+			}
+			if want, got := test.expectedOutput, sb.String(); want != got {
+				tt.Errorf("invalid output; want %q, got %q", want, got)
+			}
+			if want, got := int64(len(test.expectedOutput)), summary.Bytes; want != got {
+				tt.Errorf("invalid number of bytes; want %v, got %v", want, got)
+			}
+			if want, got := test.expectedLines, summary.Lines; want != got {
+				tt.Errorf("invalid number of lines; want %v, got %v", want, got)
+			}
+			if want, got := test.expectedTruncated, summary.Truncated; want != got {
+				tt.Errorf("invalid truncated flag; want %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func Test_WriteJobActionOutput_largeTail(t *testing.T) {
+	m := newMockCircleClient("github", "influxdata", "testproject")
+	var messages []circle.JobOutputMessage
+	for i := 0; i < 10000; i++ {
+		messages = append(messages, circle.JobOutputMessage{Message: "some output\n"})
+	}
+	messages = append(messages, circle.JobOutputMessage{Message: "last line\n"})
+	m.jobOutputMap["output"] = messages
+
+	var sb strings.Builder
+	summary, err := WriteJobActionOutput(context.Background(), m, &circle.JobAction{OutputURL: "output", HasOutput: true}, &sb, OutputLimits{MaxLines: 3, TailOnly: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := "some output\nsome output\nlast line\n", sb.String(); want != got {
+		t.Errorf("invalid output; want %q, got %q", want, got)
+	}
+	if !summary.Truncated {
+		t.Errorf("expected output to be truncated")
+	}
+}
@@ -0,0 +1,243 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+)
+
+// DefaultHeartbeatInterval is how often a summary is logged while waiting, if nothing else was logged.
+const DefaultHeartbeatInterval = time.Minute
+
+// progressLogger logs progress of waiting for workflows based on events emitted by WaitForJobs ; unless verbose,
+// it only logs changes, along with a periodic heartbeat so that CircleCI does not time out due to lack of output.
+type progressLogger struct {
+	sugar             *zap.SugaredLogger
+	verbose           bool
+	heartbeatInterval time.Duration
+	lastOutput        time.Time
+	now               func() time.Time
+	// matrixSummaries holds the last logged summary of each matrix job, by workflow ID and matrix job name
+	matrixSummaries map[string]string
+}
+
+func newProgressLogger(logger *zap.Logger, verbose bool, heartbeatInterval time.Duration) *progressLogger {
+	if heartbeatInterval <= 0 {
+		heartbeatInterval = DefaultHeartbeatInterval
+	}
+	return &progressLogger{
+		sugar:             logger.Sugar(),
+		verbose:           verbose,
+		heartbeatInterval: heartbeatInterval,
+		now:               time.Now,
+		matrixSummaries:   map[string]string{},
+	}
+}
+
+// handleEvent logs an event.
+func (p *progressLogger) handleEvent(event Event) {
+	switch e := event.(type) {
+	case WorkflowAppeared:
+		if !p.verbose && !circle.WorkflowFinished(e.Workflow) {
+			p.sugar.Infof("workflow %s has not finished yet (status: %s)", e.Workflow.Name, e.Workflow.Status)
+			p.lastOutput = p.now()
+		}
+	case JobStatusChanged:
+		if !p.verbose {
+			p.logJobStatusChanged(e)
+		}
+	case WorkflowFinished:
+		if !p.verbose {
+			p.logWorkflowFinished(e)
+		}
+	case PollCompleted:
+		if p.verbose {
+			p.logAllStatus(e.Result)
+		}
+		if e.NextPoll > 0 {
+			p.logWaiting(e.Result, e.NextPoll)
+		}
+	case WaitFinished:
+		p.logWaitFinished(e)
+	}
+}
+
+// logWaiting logs that not everything has finished yet, which is done on every check if verbose
+// and as a heartbeat with counts of workflows and jobs otherwise.
+func (p *progressLogger) logWaiting(result *WorkflowsSummary, duration time.Duration) {
+	if p.verbose {
+		p.sugar.Infof("Not all workflows / jobs have finished, waiting for %g seconds", math.Round(duration.Seconds()))
+		return
+	}
+
+	if p.now().Sub(p.lastOutput) < p.heartbeatInterval {
+		return
+	}
+
+	succeeded, failed, pending := 0, 0, 0
+	for _, details := range result.AllWorkflows {
+		succeeded += len(details.SucceededJobs)
+		failed += len(details.FailedJobs) + len(details.AllowedFailedJobs)
+		pending += len(details.PendingJobs)
+	}
+	p.sugar.Infof(
+		"still waiting: %d of %d workflows not finished, %d jobs pending, %d succeeded, %d failed",
+		len(result.PendingWorkflows), len(result.AllWorkflows), pending, succeeded, failed,
+	)
+	p.lastOutput = p.now()
+}
+
+// logWorkflowFinished logs that a workflow has finished.
+func (p *progressLogger) logWorkflowFinished(e WorkflowFinished) {
+	workflow := e.Details.Workflow
+	if e.Details.Failed {
+		p.sugar.Warnf("workflow %s failed (status: %s)", workflow.Name, workflow.Status)
+	} else {
+		p.sugar.Infof("workflow %s finished (status: %s)", workflow.Name, workflow.Status)
+	}
+	for _, matrixJob := range e.Details.MatrixJobs {
+		if len(matrixJob.FailedVariants) > 0 || matrixJob.AllowedFailed > 0 {
+			p.sugar.Warnf("  - %s", matrixJob)
+		}
+	}
+	p.lastOutput = p.now()
+}
+
+// logJobStatusChanged logs a job that has changed its status ; jobs that are queued or blocked are not logged.
+// Variants of matrix jobs are not logged individually, instead the summary of the matrix job is logged if it has changed.
+func (p *progressLogger) logJobStatusChanged(e JobStatusChanged) {
+	job, workflow := e.Job, e.Workflow
+	if e.MatrixJob != nil {
+		p.logMatrixJobChanged(workflow, e.MatrixJob)
+		return
+	}
+
+	switch {
+	case circle.JobFailed(job) && e.AllowedToFail:
+		p.sugar.Warnf("job %s in workflow %s failed, but is allowed to fail (status: %s%s)", job.Name, workflow.Name, job.Status, describeJobDuration(job))
+	case circle.JobFailed(job):
+		p.sugar.Warnf("job %s in workflow %s failed (status: %s%s)", job.Name, workflow.Name, job.Status, describeJobDuration(job))
+	case circle.JobFinished(job):
+		p.sugar.Infof("job %s in workflow %s finished (status: %s%s)", job.Name, workflow.Name, job.Status, describeJobDuration(job))
+	case circle.JobAwaitingApproval(job):
+		p.sugar.Infof("job %s in workflow %s is waiting for approval", job.Name, workflow.Name)
+	case job.Status == "running":
+		p.sugar.Infof("job %s in workflow %s started", job.Name, workflow.Name)
+	default:
+		return
+	}
+	p.lastOutput = p.now()
+}
+
+// logMatrixJobChanged logs the summary of a matrix job, unless it is the same as when it was last logged ; multiple
+// variants changing their status in the same check result in a single line.
+func (p *progressLogger) logMatrixJobChanged(workflow *circle.Workflow, matrixJob *MatrixJob) {
+	key := workflow.ID + "/" + matrixJob.Name
+	summary := matrixJob.String()
+	if p.matrixSummaries[key] == summary {
+		return
+	}
+	p.matrixSummaries[key] = summary
+
+	if len(matrixJob.FailedVariants) > 0 {
+		p.sugar.Warnf("%s in workflow %s", summary, workflow.Name)
+	} else {
+		p.sugar.Infof("%s in workflow %s", summary, workflow.Name)
+	}
+	p.lastOutput = p.now()
+}
+
+// logWaitFinished logs the result of waiting for workflows.
+func (p *progressLogger) logWaitFinished(e WaitFinished) {
+	switch {
+	case e.Err != nil || e.Result == nil:
+		return
+	case !e.Result.Finished:
+		p.sugar.Warnf("one or workflows has failed and should fail on error - exiting")
+	case e.Result.Failed:
+		p.sugar.Warnf("all workflows finished - failed")
+	default:
+		p.sugar.Infof("all workflows finished - successfully")
+	}
+}
+
+// describeJobDuration returns duration of a finished job for including in log messages, or an empty string if not known.
+func describeJobDuration(job *circle.Job) string {
+	startedAt, err := time.Parse(time.RFC3339, job.StartedAt)
+	if err != nil {
+		return ""
+	}
+	stoppedAt, err := time.Parse(time.RFC3339, job.StoppedAt)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf(", duration: %v", stoppedAt.Sub(startedAt).Round(time.Second))
+}
+
+// logAllStatus logs status of all workflows and jobs.
+func (p *progressLogger) logAllStatus(result *WorkflowsSummary) {
+	// report all workflows - starting with successful ones
+	for _, workflowDetails := range result.SucceededWorkflows {
+		p.sugar.Infof("workflow %s finished (status: %s)", workflowDetails.Workflow.Name, workflowDetails.Workflow.Status)
+		grouped := matrixJobNames(workflowDetails.MatrixJobs)
+		for _, job := range workflowDetails.AllowedFailedJobs {
+			if !grouped[job.Name] {
+				p.sugar.Warnf("  - job %s failed, but is allowed to fail (status: %s)", job.Name, job.Status)
+			}
+		}
+		for _, matrixJob := range workflowDetails.MatrixJobs {
+			if matrixJob.AllowedFailed > 0 {
+				p.sugar.Warnf("  - %s", matrixJob)
+			}
+		}
+	}
+
+	for _, workflow := range result.FailedWorkflows {
+		p.sugar.Warnf("workflow %s failed (status: %s)", workflow.Workflow.Name, workflow.Workflow.Status)
+		for _, matrixJob := range workflow.MatrixJobs {
+			p.sugar.Warnf("  - %s", matrixJob)
+		}
+	}
+
+	for _, details := range result.PendingWorkflows {
+		p.sugar.Infof("workflow %s has not finished yet (status: %s)", details.Workflow.Name, details.Workflow.Status)
+		// jobs of matrix jobs are reported as a single line per matrix job
+		grouped := matrixJobNames(details.MatrixJobs)
+		for _, job := range details.SucceededJobs {
+			if !grouped[job.Name] {
+				p.sugar.Infof("  - job %s finished (status: %s)", job.Name, job.Status)
+			}
+		}
+		for _, job := range details.PendingJobs {
+			if grouped[job.Name] {
+				continue
+			}
+			if circle.JobAwaitingApproval(job) {
+				p.sugar.Infof("  - job %s is waiting for approval (status: %s)", job.Name, job.Status)
+			} else {
+				p.sugar.Infof("  - job %s in progress (status: %s)", job.Name, job.Status)
+			}
+		}
+		for _, job := range details.FailedJobs {
+			if !grouped[job.Name] {
+				p.sugar.Warnf("  - job %s failed (status: %s)", job.Name, job.Status)
+			}
+		}
+		for _, job := range details.AllowedFailedJobs {
+			if !grouped[job.Name] {
+				p.sugar.Warnf("  - job %s failed, but is allowed to fail (status: %s)", job.Name, job.Status)
+			}
+		}
+		for _, matrixJob := range details.MatrixJobs {
+			if len(matrixJob.FailedVariants) > 0 {
+				p.sugar.Warnf("  - %s", matrixJob)
+			} else {
+				p.sugar.Infof("  - %s", matrixJob)
+			}
+		}
+	}
+	p.lastOutput = p.now()
+}
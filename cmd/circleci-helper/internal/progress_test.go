@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newProgressTestSummary(buildStatus string, testStatus string) *WorkflowsSummary {
+	workflow := &WorkflowDetails{
+		Workflow: &circle.Workflow{ID: "1", Name: "build", Status: "running"},
+	}
+	for _, job := range []*circle.Job{
+		{Name: "build", Status: buildStatus, StartedAt: "2021-01-01T00:00:00Z", StoppedAt: "2021-01-01T00:01:30Z"},
+		{Name: "test", Status: testStatus},
+	} {
+		if circle.JobFinished(job) {
+			workflow.SucceededJobs = append(workflow.SucceededJobs, job)
+		} else {
+			workflow.PendingJobs = append(workflow.PendingJobs, job)
+		}
+	}
+	return &WorkflowsSummary{
+		AllWorkflows:     []*WorkflowDetails{workflow},
+		PendingWorkflows: []*WorkflowDetails{workflow},
+	}
+}
+
+func observedMessages(logs *observer.ObservedLogs) []string {
+	var result []string
+	for _, entry := range logs.TakeAll() {
+		result = append(result, entry.Message)
+	}
+	return result
+}
+
+// logProgress passes events for a check of workflows to progress logger, the same way WaitForJobs does.
+func logProgress(p *progressLogger, tracker *eventTracker, result *WorkflowsSummary, nextPoll time.Duration) {
+	for _, event := range tracker.changes(result) {
+		p.handleEvent(event)
+	}
+	p.handleEvent(PollCompleted{Result: result, NextPoll: nextPoll})
+}
+
+func Test_progressLogger_transitions(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := newProgressLogger(zap.New(core), false, time.Minute)
+	p.now = func() time.Time { return now }
+	tracker := newEventTracker()
+
+	logProgress(p, tracker, newProgressTestSummary("running", "blocked"), 10*time.Second)
+	want := []string{
+		"workflow build has not finished yet (status: running)",
+		"job build in workflow build started",
+	}
+	if got := observedMessages(logs); !reflect.DeepEqual(want, got) {
+		t.Errorf("invalid messages; want %q, got %q", want, got)
+	}
+
+	// nothing has changed, so neither status nor heartbeat should be logged
+	logProgress(p, tracker, newProgressTestSummary("running", "blocked"), 10*time.Second)
+	if got := observedMessages(logs); len(got) != 0 {
+		t.Errorf("expected no messages, got %q", got)
+	}
+
+	logProgress(p, tracker, newProgressTestSummary("success", "running"), 10*time.Second)
+	want = []string{
+		"job build in workflow build finished (status: success, duration: 1m30s)",
+		"job test in workflow build started",
+	}
+	if got := observedMessages(logs); !reflect.DeepEqual(want, got) {
+		t.Errorf("invalid messages; want %q, got %q", want, got)
+	}
+
+	// heartbeat should be logged once nothing was logged for heartbeat interval
+	now = now.Add(time.Minute)
+	logProgress(p, tracker, newProgressTestSummary("success", "running"), 10*time.Second)
+	want = []string{
+		"still waiting: 1 of 1 workflows not finished, 1 jobs pending, 1 succeeded, 0 failed",
+	}
+	if got := observedMessages(logs); !reflect.DeepEqual(want, got) {
+		t.Errorf("invalid messages; want %q, got %q", want, got)
+	}
+}
+
+func Test_progressLogger_verbose(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	p := newProgressLogger(zap.New(core), true, 0)
+	tracker := newEventTracker()
+
+	for i := 0; i < 2; i++ {
+		logProgress(p, tracker, newProgressTestSummary("success", "running"), 10*time.Second)
+		want := []string{
+			"workflow build has not finished yet (status: running)",
+			"  - job build finished (status: success)",
+			"  - job test in progress (status: running)",
+			"Not all workflows / jobs have finished, waiting for 10 seconds",
+		}
+		if got := observedMessages(logs); !reflect.DeepEqual(want, got) {
+			t.Errorf("invalid messages; want %q, got %q", want, got)
+		}
+	}
+}
+
+func Test_progressLogger_matrixJobs(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	p := newProgressLogger(zap.New(core), false, time.Minute)
+	tracker := newEventTracker()
+	matrix, err := NewMatrixGrouper([]string{"test-<< matrix.os >>"}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	summary := func(workflowStatus string, statuses ...string) *WorkflowsSummary {
+		workflow := &WorkflowDetails{
+			Workflow: &circle.Workflow{ID: "1", Name: "build", Status: workflowStatus},
+		}
+		for i, status := range statuses {
+			job := &circle.Job{Name: []string{"test-linux", "test-darwin", "test-windows"}[i], Status: status}
+			switch {
+			case circle.JobFailed(job):
+				workflow.FailedJobs = append(workflow.FailedJobs, job)
+				workflow.Failed = true
+			case circle.JobFinished(job):
+				workflow.SucceededJobs = append(workflow.SucceededJobs, job)
+			default:
+				workflow.PendingJobs = append(workflow.PendingJobs, job)
+			}
+		}
+		workflow.MatrixJobs = matrix.GroupJobs(workflow)
+		return &WorkflowsSummary{AllWorkflows: []*WorkflowDetails{workflow}}
+	}
+
+	logProgress(p, tracker, summary("running", "running", "running", "running"), 0)
+	want := []string{
+		"workflow build has not finished yet (status: running)",
+		"matrix job test-<< matrix.os >>: 0 succeeded, 0 failed, 3 pending in workflow build",
+	}
+	if got := observedMessages(logs); !reflect.DeepEqual(want, got) {
+		t.Errorf("invalid messages; want %q, got %q", want, got)
+	}
+
+	// multiple variants changing their status are logged as a single line
+	logProgress(p, tracker, summary("running", "success", "failed", "running"), 0)
+	want = []string{
+		"matrix job test-<< matrix.os >>: 1 succeeded, 1 failed, 1 pending (failed: test-darwin) in workflow build",
+	}
+	if got := observedMessages(logs); !reflect.DeepEqual(want, got) {
+		t.Errorf("invalid messages; want %q, got %q", want, got)
+	}
+
+	logProgress(p, tracker, summary("failed", "success", "failed", "success"), 0)
+	want = []string{
+		"matrix job test-<< matrix.os >>: 2 succeeded, 1 failed, 0 pending (failed: test-darwin) in workflow build",
+		"workflow build failed (status: failed)",
+		"  - matrix job test-<< matrix.os >>: 2 succeeded, 1 failed, 0 pending (failed: test-darwin)",
+	}
+	if got := observedMessages(logs); !reflect.DeepEqual(want, got) {
+		t.Errorf("invalid messages; want %q, got %q", want, got)
+	}
+}
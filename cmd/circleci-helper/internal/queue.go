@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+)
+
+// WaitForQueueOptions allows passing options for waiting until older pipelines no longer hold a lock.
+type WaitForQueueOptions struct {
+	ProjectType    string
+	Org            string
+	Project        string
+	PipelineNumber int
+	// Branch that the lock is scoped to, branch of the current pipeline if not set.
+	Branch string
+	// WorkflowNames, IncludeWorkflows and ExcludeWorkflows scope the lock to matching workflows of older pipelines.
+	WorkflowNames    []string
+	IncludeWorkflows []string
+	ExcludeWorkflows []string
+	// JobName scopes the lock to a job, older pipelines release the lock once it has finished instead of when their workflows finish ;
+	// workflows that do not contain the job do not hold the lock.
+	JobName string
+	// MaxPipelines limits how many of the most recent pipelines on the branch are checked, if set.
+	MaxPipelines int
+	WaitDuration *WaitForJobsDuration
+	// MaxWait is how long to wait for older pipelines, until the context is canceled if not set.
+	MaxWait time.Duration
+	// DontQuit returns without an error once MaxWait has passed, instead of failing.
+	DontQuit bool
+}
+
+// WaitForQueue waits until none of the older pipelines on the same branch hold the lock, such as when deploying
+// should only happen from one pipeline at a time. Older pipelines hold the lock until all of their matching
+// workflows, or the job specified in JobName, have finished.
+func WaitForQueue(ctx context.Context, logger *zap.Logger, client circle.Client, opts WaitForQueueOptions) error {
+	sugar := logger.Sugar()
+
+	workflowFilter, err := newWorkflowFilter(opts.WorkflowNames, opts.IncludeWorkflows, opts.ExcludeWorkflows)
+	if err != nil {
+		return err
+	}
+
+	var filterJob func(workflow *circle.Workflow, job *circle.Job) bool
+	if opts.JobName != "" {
+		filterJob = func(workflow *circle.Workflow, job *circle.Job) bool {
+			return job.Name == opts.JobName
+		}
+	}
+
+	pipelines, branch, err := getOlderPipelines(ctx, client, opts.ProjectType, opts.Org, opts.Project, opts.PipelineNumber, opts.Branch, opts.MaxPipelines)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	lastBlocking := ""
+
+	// loop until older pipelines release the lock, timeout is handled by the context and MaxWait
+	for {
+		var blocking []*circle.Pipeline
+		for _, pipeline := range pipelines {
+			result, err := checkWorkflowsStatus(ctx, client, pipeline.ID, checkWorkflowStatusOpts{
+				filterWorkflow:    filterWorkflowByName(workflowFilter, nil),
+				filterJob:         filterJob,
+				pendingJobDetails: opts.JobName != "",
+			})
+			if err != nil {
+				return err
+			}
+			if !result.Finished {
+				blocking = append(blocking, pipeline)
+			}
+		}
+
+		if len(blocking) == 0 {
+			sugar.Infof("no older pipelines on branch %s are running, proceeding", branch)
+			return nil
+		}
+
+		// only log when the list of pipelines being waited for changes
+		numbers := make([]string, len(blocking))
+		for i, pipeline := range blocking {
+			numbers[i] = fmt.Sprintf("%d", pipeline.Number)
+		}
+		if description := strings.Join(numbers, ", "); description != lastBlocking {
+			sugar.Infof("waiting for %d older pipelines on branch %s: %s", len(blocking), branch, description)
+			lastBlocking = description
+		}
+
+		if opts.MaxWait > 0 && time.Since(start) >= opts.MaxWait {
+			if opts.DontQuit {
+				sugar.Warnf("older pipelines still running after %v, proceeding anyway: %s", opts.MaxWait, lastBlocking)
+				return nil
+			}
+			return fmt.Errorf("older pipelines still running after %v: %s", opts.MaxWait, lastBlocking)
+		}
+
+		// pipelines that have released the lock do not need to be checked again
+		pipelines = blocking
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(opts.WaitDuration.GetDuration(len(blocking))):
+		}
+	}
+}
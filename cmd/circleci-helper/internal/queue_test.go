@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+)
+
+func Test_WaitForQueue(t *testing.T) {
+	newClient := func() *mockCircleClient {
+		m := newMockCircleClient("github", "influxdata", "testproject")
+		m.addPipelineForBranch(10, "p10", "main")
+		m.addPipelineForBranch(11, "p11", "feature")
+		m.addPipelineForBranch(12, "p12", "main")
+		m.addPipelineForBranch(13, "p13", "main")
+		m.addWorkflows("p10", []*circle.Workflow{
+			{ID: "p10-deploy", Name: "deploy", Status: "success", CreatedAt: "2021-01-01T00:00:00.000Z"},
+		})
+		m.addWorkflows("p11", []*circle.Workflow{
+			{ID: "p11-deploy", Name: "deploy", Status: "running", CreatedAt: "2021-01-01T00:00:00.000Z"},
+		})
+		m.addWorkflows("p12", []*circle.Workflow{
+			{ID: "p12-deploy", Name: "deploy", Status: "running", CreatedAt: "2021-01-01T00:00:00.000Z"},
+			{ID: "p12-test", Name: "test", Status: "success", CreatedAt: "2021-01-01T00:00:00.000Z"},
+		})
+		m.addJobs("p12-deploy", []*circle.Job{
+			{ID: "p12-deploy-1", Name: "build", Status: "success"},
+			{ID: "p12-deploy-2", Name: "publish", Status: "running"},
+		})
+		return m
+	}
+
+	for _, test := range []struct {
+		name          string
+		opts          func(opts *WaitForQueueOptions)
+		expectedError bool
+	}{
+		{
+			name:          "older pipeline running",
+			opts:          func(opts *WaitForQueueOptions) {},
+			expectedError: true,
+		},
+		{
+			name:          "older pipeline running with dont quit",
+			opts:          func(opts *WaitForQueueOptions) { opts.DontQuit = true },
+			expectedError: false,
+		},
+		{
+			name:          "lock scoped to finished workflow",
+			opts:          func(opts *WaitForQueueOptions) { opts.WorkflowNames = []string{"test"} },
+			expectedError: false,
+		},
+		{
+			name:          "lock scoped to finished job",
+			opts:          func(opts *WaitForQueueOptions) { opts.JobName = "build" },
+			expectedError: false,
+		},
+		{
+			name:          "lock scoped to running job",
+			opts:          func(opts *WaitForQueueOptions) { opts.JobName = "publish" },
+			expectedError: true,
+		},
+		{
+			name:          "lock scoped to other branch",
+			opts:          func(opts *WaitForQueueOptions) { opts.Branch = "other" },
+			expectedError: false,
+		},
+		{
+			name:          "older pipeline not checked",
+			opts:          func(opts *WaitForQueueOptions) { opts.MaxPipelines = 1 },
+			expectedError: false,
+		},
+	} {
+		t.Run(test.name, func(tt *testing.T) {
+			opts := WaitForQueueOptions{
+				ProjectType:    "github",
+				Org:            "influxdata",
+				Project:        "testproject",
+				PipelineNumber: 13,
+				WaitDuration:   NewWaitForJobsDuration(time.Millisecond),
+				MaxWait:        5 * time.Millisecond,
+			}
+			test.opts(&opts)
+
+			err := WaitForQueue(context.Background(), zap.NewNop(), newClient(), opts)
+			if test.expectedError && err == nil {
+				tt.Errorf("expected an error")
+			} else if !test.expectedError && err != nil {
+				tt.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func Test_WaitForQueue_canceled(t *testing.T) {
+	m := newMockCircleClient("github", "influxdata", "testproject")
+	m.addPipelineForBranch(10, "p10", "main")
+	m.addPipelineForBranch(11, "p11", "main")
+	m.addWorkflows("p10", []*circle.Workflow{
+		{ID: "p10-deploy", Name: "deploy", Status: "running", CreatedAt: "2021-01-01T00:00:00.000Z"},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// waiting between checks should stop as soon as the context is done
+	start := time.Now()
+	err := WaitForQueue(ctx, zap.NewNop(), m, WaitForQueueOptions{
+		ProjectType:    "github",
+		Org:            "influxdata",
+		Project:        "testproject",
+		PipelineNumber: 11,
+		WaitDuration:   NewWaitForJobsDuration(time.Hour),
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("invalid error; want %v, got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("waiting did not stop when the context was done, took %v", elapsed)
+	}
+}
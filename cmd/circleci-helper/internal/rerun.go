@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+)
+
+// RerunWorkflowsOptions allows passing options for rerunning one or more workflows.
+type RerunWorkflowsOptions struct {
+	ProjectType      string
+	Org              string
+	Project          string
+	PipelineNumber   int
+	WorkflowNames    []string
+	IncludeWorkflows []string
+	ExcludeWorkflows []string
+	// FromFailed reruns workflows from failed jobs, only rerunning workflows that have failed.
+	FromFailed bool
+	// Jobs lists names of jobs to rerun, only rerunning workflows that contain at least one of them.
+	Jobs []string
+	// SparseTree only reruns jobs listed in Jobs and jobs that depend on them.
+	SparseTree bool
+	// EnableSSH enables SSH access to jobs listed in Jobs.
+	EnableSSH bool
+}
+
+// RerunWorkflowResult describes a workflow that was rerun.
+type RerunWorkflowResult struct {
+	Workflow      *circle.Workflow `json:"workflow"`
+	NewWorkflowID string           `json:"new_workflow_id"`
+}
+
+// validateRerunWorkflowsOptions checks that options can be combined in a single request to CircleCI API.
+func validateRerunWorkflowsOptions(opts RerunWorkflowsOptions) error {
+	if opts.FromFailed && len(opts.Jobs) > 0 {
+		return fmt.Errorf("rerunning from failed jobs cannot be combined with rerunning specific jobs")
+	}
+	if opts.SparseTree && len(opts.Jobs) == 0 {
+		return fmt.Errorf("rerunning with sparse tree requires specific jobs to rerun")
+	}
+	if opts.EnableSSH && len(opts.Jobs) == 0 {
+		return fmt.Errorf("rerunning with SSH enabled requires specific jobs to rerun")
+	}
+	return nil
+}
+
+// RerunWorkflows reruns workflows matching criteria that have finished, returning the workflows that were rerun.
+// If specific jobs are requested, all of them have to be found in at least one of the workflows.
+func RerunWorkflows(ctx context.Context, logger *zap.Logger, client circle.Client, opts RerunWorkflowsOptions) ([]*RerunWorkflowResult, error) {
+	sugar := logger.Sugar()
+
+	if err := validateRerunWorkflowsOptions(opts); err != nil {
+		return nil, err
+	}
+
+	workflowFilter, err := newWorkflowFilter(opts.WorkflowNames, opts.IncludeWorkflows, opts.ExcludeWorkflows)
+	if err != nil {
+		return nil, err
+	}
+
+	pipelineID, err := client.GetPipelineID(ctx, opts.ProjectType, opts.Org, opts.Project, opts.PipelineNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	workflows, err := getLatestWorkflows(ctx, client, pipelineID, filterWorkflowByName(workflowFilter, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	// resolve everything that should be rerun before rerunning anything, so that invalid job names do not cause partial reruns
+	type rerun struct {
+		workflow *circle.Workflow
+		jobIDs   []string
+	}
+	var reruns []rerun
+	foundJobs := map[string]bool{}
+	for _, workflow := range workflows {
+		if !circle.WorkflowFinished(workflow) {
+			sugar.Infof("skipping workflow %s as it has not finished yet (status: %s)", workflow.Name, workflow.Status)
+			continue
+		}
+		if opts.FromFailed && !circle.WorkflowFailed(workflow) {
+			sugar.Infof("skipping workflow %s as it has not failed (status: %s)", workflow.Name, workflow.Status)
+			continue
+		}
+
+		var jobIDs []string
+		if len(opts.Jobs) > 0 {
+			jobs, err := client.GetWorkflowJobs(ctx, workflow.ID)
+			if err != nil {
+				return nil, err
+			}
+			jobIDs = resolveJobIDs(jobs, opts.Jobs, foundJobs)
+			if len(jobIDs) == 0 {
+				continue
+			}
+		}
+
+		reruns = append(reruns, rerun{workflow: workflow, jobIDs: jobIDs})
+	}
+
+	var missing []string
+	for _, name := range opts.Jobs {
+		if !foundJobs[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("jobs not found in any of the workflows: %s", strings.Join(missing, ", "))
+	}
+
+	result := []*RerunWorkflowResult{}
+	for _, r := range reruns {
+		sugar.Infof("rerunning workflow %s (id: %s, status: %s)", r.workflow.Name, r.workflow.ID, r.workflow.Status)
+		newID, err := client.RerunWorkflow(ctx, r.workflow.ID, circle.RerunWorkflowOptions{
+			FromFailed: opts.FromFailed,
+			Jobs:       r.jobIDs,
+			SparseTree: opts.SparseTree,
+			EnableSSH:  opts.EnableSSH,
+		})
+		if err != nil {
+			return result, err
+		}
+		result = append(result, &RerunWorkflowResult{Workflow: r.workflow, NewWorkflowID: newID})
+	}
+
+	return result, nil
+}
+
+// resolveJobIDs returns IDs of jobs with specified names, marking names that were found.
+func resolveJobIDs(jobs []*circle.Job, names []string, found map[string]bool) []string {
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	var result []string
+	for _, job := range jobs {
+		if wanted[job.Name] {
+			found[job.Name] = true
+			result = append(result, job.ID)
+		}
+	}
+	return result
+}
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+)
+
+func Test_RerunWorkflows(t *testing.T) {
+	baseOpts := RerunWorkflowsOptions{
+		ProjectType:    "github",
+		Org:            "influxdata",
+		Project:        "testproject",
+		PipelineNumber: 123,
+	}
+
+	for _, test := range []struct {
+		name            string
+		workflow1Status string
+		workflow2Status string
+		opts            func(opts *RerunWorkflowsOptions)
+		expectedRerun   []string
+		expectedOpts    []circle.RerunWorkflowOptions
+		expectedError   bool
+	}{
+		{
+			name:            "all finished workflows",
+			workflow1Status: "success",
+			workflow2Status: "running",
+			opts:            func(opts *RerunWorkflowsOptions) {},
+			expectedRerun:   []string{"456-2"},
+			expectedOpts:    []circle.RerunWorkflowOptions{{}},
+		},
+		{
+			name:            "from failed",
+			workflow1Status: "success",
+			workflow2Status: "failed",
+			opts:            func(opts *RerunWorkflowsOptions) { opts.FromFailed = true },
+			expectedRerun:   []string{"456-4"},
+			expectedOpts:    []circle.RerunWorkflowOptions{{FromFailed: true}},
+		},
+		{
+			name:            "specific jobs with SSH",
+			workflow1Status: "failed",
+			workflow2Status: "failed",
+			opts: func(opts *RerunWorkflowsOptions) {
+				opts.Jobs = []string{"test-job-4-1"}
+				opts.EnableSSH = true
+				opts.SparseTree = true
+			},
+			expectedRerun: []string{"456-4"},
+			expectedOpts:  []circle.RerunWorkflowOptions{{Jobs: []string{"456-4-1"}, EnableSSH: true, SparseTree: true}},
+		},
+		{
+			name:            "filtered workflows",
+			workflow1Status: "failed",
+			workflow2Status: "failed",
+			opts:            func(opts *RerunWorkflowsOptions) { opts.WorkflowNames = []string{"test-workflow-1"} },
+			expectedRerun:   []string{"456-2"},
+			expectedOpts:    []circle.RerunWorkflowOptions{{}},
+		},
+		{
+			name:            "unknown job",
+			workflow1Status: "failed",
+			workflow2Status: "failed",
+			opts:            func(opts *RerunWorkflowsOptions) { opts.Jobs = []string{"test-job-4-1", "other-job"} },
+			expectedError:   true,
+		},
+		{
+			name:            "SSH without jobs",
+			workflow1Status: "failed",
+			workflow2Status: "failed",
+			opts:            func(opts *RerunWorkflowsOptions) { opts.EnableSSH = true },
+			expectedError:   true,
+		},
+		{
+			name:            "from failed with jobs",
+			workflow1Status: "failed",
+			workflow2Status: "failed",
+			opts: func(opts *RerunWorkflowsOptions) {
+				opts.FromFailed = true
+				opts.Jobs = []string{"test-job-4-1"}
+			},
+			expectedError: true,
+		},
+	} {
+		t.Run(test.name, func(tt *testing.T) {
+			m := newMockCircleClientWithData(test.workflow1Status, test.workflow2Status, "success", "success")
+			opts := baseOpts
+			test.opts(&opts)
+
+			result, err := RerunWorkflows(context.Background(), zap.NewNop(), m, opts)
+			if test.expectedError {
+				if err == nil {
+					tt.Errorf("expected an error")
+				}
+				if len(m.rerun) > 0 {
+					tt.Errorf("expected no workflows to be rerun, got %v", m.rerun)
+				}
+				return
+			}
+			if err != nil {
+				tt.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(test.expectedRerun, m.rerun) {
+				tt.Errorf("invalid workflows rerun; want %v, got %v", test.expectedRerun, m.rerun)
+			}
+			if !reflect.DeepEqual(test.expectedOpts, m.rerunOpts) {
+				tt.Errorf("invalid rerun options; want %+v, got %+v", test.expectedOpts, m.rerunOpts)
+			}
+			if want, got := len(test.expectedRerun), len(result); want != got {
+				tt.Fatalf("invalid number of results; want %v, got %v", want, got)
+			}
+			if want, got := test.expectedRerun[0]+"-rerun-1", result[0].NewWorkflowID; want != got {
+				tt.Errorf("invalid new workflow ID; want %v, got %v", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+)
+
+// RetriedJob describes a job that only passed after its workflow was rerun.
+type RetriedJob struct {
+	Workflow *circle.Workflow `json:"workflow"`
+	Job      *circle.Job      `json:"job"`
+	Retries  int              `json:"retries"`
+}
+
+// workflowRetrier reruns failed workflows from failed jobs, keeping track of what was rerun.
+type workflowRetrier struct {
+	maxRetries int
+	// number of reruns for each workflow name
+	retries map[string]int
+	// IDs of workflow instances that were already rerun
+	rerunIDs map[string]bool
+	// names of jobs that failed before a rerun, for each workflow name
+	failedJobs map[string]map[string]bool
+}
+
+func newWorkflowRetrier(maxRetries int) *workflowRetrier {
+	return &workflowRetrier{
+		maxRetries: maxRetries,
+		retries:    map[string]int{},
+		rerunIDs:   map[string]bool{},
+		failedJobs: map[string]map[string]bool{},
+	}
+}
+
+// canRetry returns whether specified workflow can still be rerun.
+func (r *workflowRetrier) canRetry(workflow *circle.Workflow) bool {
+	return r.retries[workflow.Name] < r.maxRetries
+}
+
+// retryFailedWorkflows reruns failed workflows that have retries left and updates result so that
+// these workflows are reported as pending until their new instance finishes.
+func (r *workflowRetrier) retryFailedWorkflows(ctx context.Context, logger *zap.Logger, client circle.Client, result *WorkflowsSummary) error {
+	sugar := logger.Sugar()
+
+	var failedWorkflows []*WorkflowDetails
+	for _, details := range result.FailedWorkflows {
+		workflow := details.Workflow
+		switch {
+		case r.rerunIDs[workflow.ID]:
+			// workflow was already rerun, but its new instance was not returned yet
+			sugar.Infof("workflow %s was rerun, waiting for it to start", workflow.Name)
+		case workflow.Status == "failed" && r.canRetry(workflow):
+			sugar.Warnf("workflow %s failed, rerunning from failed jobs (retry %d of %d)", workflow.Name, r.retries[workflow.Name]+1, r.maxRetries)
+			newID, err := client.RerunWorkflow(ctx, workflow.ID, circle.RerunWorkflowOptions{FromFailed: true})
+			if err != nil {
+				return err
+			}
+			sugar.Infof("workflow %s was rerun as %s", workflow.Name, newID)
+
+			r.retries[workflow.Name]++
+			r.rerunIDs[workflow.ID] = true
+			if r.failedJobs[workflow.Name] == nil {
+				r.failedJobs[workflow.Name] = map[string]bool{}
+			}
+			for _, job := range details.FailedJobs {
+				r.failedJobs[workflow.Name][job.Name] = true
+			}
+		default:
+			failedWorkflows = append(failedWorkflows, details)
+			continue
+		}
+
+		// report the workflow as pending until the new instance finishes
+		result.PendingWorkflows = append(result.PendingWorkflows, &WorkflowDetails{Workflow: workflow})
+		result.Finished = false
+	}
+	result.FailedWorkflows = failedWorkflows
+
+	// only consider the result failed if failures cannot be retried anymore ; workflows with failed jobs
+	// that can be retried are not finished until the workflow finishes and can be rerun
+	result.Failed = len(result.FailedWorkflows) > 0
+	for _, details := range result.PendingWorkflows {
+		if len(details.FailedJobs) > 0 {
+			if r.canRetry(details.Workflow) {
+				result.Finished = false
+			} else {
+				result.Failed = true
+			}
+		}
+	}
+
+	return nil
+}
+
+// passedAfterRetry returns jobs from succeeded workflows that have failed before their workflow was rerun.
+func (r *workflowRetrier) passedAfterRetry(result *WorkflowsSummary) []*RetriedJob {
+	var jobs []*RetriedJob
+	for _, details := range result.SucceededWorkflows {
+		failedJobs := r.failedJobs[details.Workflow.Name]
+		for _, job := range details.SucceededJobs {
+			if failedJobs[job.Name] {
+				jobs = append(jobs, &RetriedJob{
+					Workflow: details.Workflow,
+					Job:      job,
+					Retries:  r.retries[details.Workflow.Name],
+				})
+			}
+		}
+	}
+	return jobs
+}
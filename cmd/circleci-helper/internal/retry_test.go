@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func Test_WaitForJobs_retryFailed(t *testing.T) {
+	for _, test := range []struct {
+		name            string
+		rerunStatus     string
+		retryFailed     int
+		expectedReruns  int
+		expectedFailed  bool
+		expectedRetried int
+	}{
+		{
+			name:            "passes after retry",
+			rerunStatus:     "success",
+			retryFailed:     2,
+			expectedReruns:  1,
+			expectedRetried: 1,
+		},
+		{
+			name:           "fails after all retries",
+			rerunStatus:    "failed",
+			retryFailed:    2,
+			expectedReruns: 2,
+			expectedFailed: true,
+		},
+	} {
+		t.Run(test.name, func(tt *testing.T) {
+			m := newMockCircleClientWithData("success", "failed", "success", "failed")
+			m.rerunStatus = test.rerunStatus
+
+			result, err := WaitForJobs(context.Background(), zap.NewNop(), m, WaitForJobsOptions{
+				ProjectType:    "github",
+				Org:            "influxdata",
+				Project:        "testproject",
+				PipelineNumber: 123,
+				RetryFailed:    test.retryFailed,
+				WaitDuration:   NewWaitForJobsDuration(time.Millisecond),
+			})
+			if err != nil {
+				tt.Fatalf("unexpected error: %v", err)
+			}
+
+			if want, got := test.expectedReruns, len(m.rerun); want != got {
+				tt.Errorf("invalid number of reruns; want %v, got %v", want, got)
+			}
+			if want, got := test.expectedFailed, result.Failed; want != got {
+				tt.Errorf("invalid value for Failed; want %v, got %v", want, got)
+			}
+			if want, got := test.expectedRetried, len(result.PassedAfterRetryJobs); want != got {
+				tt.Fatalf("invalid number of jobs passed after retry; want %v, got %v", want, got)
+			}
+			if test.expectedRetried > 0 {
+				if want, got := "test-job-4-1", result.PassedAfterRetryJobs[0].Job.Name; want != got {
+					tt.Errorf("invalid job passed after retry; want %v, got %v", want, got)
+				}
+			}
+		})
+	}
+}
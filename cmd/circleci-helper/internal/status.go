@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+)
+
+// StatusOptions allows passing options for retrieving status of a pipeline.
+type StatusOptions struct {
+	ProjectType      string
+	Org              string
+	Project          string
+	PipelineNumber   int
+	WorkflowNames    []string
+	IncludeWorkflows []string
+	ExcludeWorkflows []string
+	IncludeJobs      []string
+	ExcludeJobs      []string
+	// Where is an expression that selects workflows and jobs, see Expression for details.
+	Where string
+	// Concurrency is the number of workflows whose jobs are retrieved in parallel, DefaultConcurrency if not set.
+	Concurrency int
+}
+
+// PipelineStatus is a snapshot of status of workflows and jobs of a pipeline.
+type PipelineStatus struct {
+	ProjectType    string            `json:"project_type"`
+	Org            string            `json:"org"`
+	Project        string            `json:"project"`
+	PipelineNumber int               `json:"pipeline_number"`
+	Failed         bool              `json:"failed"`
+	Finished       bool              `json:"finished"`
+	Workflows      []*WorkflowStatus `json:"workflows"`
+}
+
+// WorkflowStatus describes status of a single workflow and its jobs in PipelineStatus.
+type WorkflowStatus struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Failed bool   `json:"failed"`
+	// Duration is how long the workflow has been running, or ran for if it has finished.
+	Duration string       `json:"duration,omitempty"`
+	URL      string       `json:"url"`
+	Jobs     []*JobStatus `json:"jobs"`
+}
+
+// JobStatus describes status of a single job in WorkflowStatus.
+type JobStatus struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	Failed bool   `json:"failed"`
+	// Number is the job number, which is 0 if the job has not started yet.
+	Number int `json:"number,omitempty"`
+	// Duration is how long the job has been running, or ran for if it has finished ; empty if it has not started yet.
+	Duration string `json:"duration,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+// WorkflowURL returns URL of a workflow in CircleCI web application.
+func WorkflowURL(projectType string, org string, project string, pipelineNumber int, workflowID string) string {
+	return fmt.Sprintf(
+		"https://app.circleci.com/pipelines/%s/%s/%s/%d/workflows/%s",
+		url.PathEscape(projectType), url.PathEscape(org), url.PathEscape(project),
+		pipelineNumber,
+		url.PathEscape(workflowID),
+	)
+}
+
+// JobURL returns URL of a job in CircleCI web application.
+func JobURL(projectType string, org string, project string, pipelineNumber int, workflowID string, jobNumber int) string {
+	return fmt.Sprintf("%s/jobs/%d", WorkflowURL(projectType, org, project, pipelineNumber, workflowID), jobNumber)
+}
+
+// GetPipelineStatus checks status of all workflows and jobs matching criteria once, without waiting for them.
+func GetPipelineStatus(ctx context.Context, client circle.Client, opts StatusOptions) (*PipelineStatus, error) {
+	return getPipelineStatus(ctx, client, opts, time.Now())
+}
+
+func getPipelineStatus(ctx context.Context, client circle.Client, opts StatusOptions, now time.Time) (*PipelineStatus, error) {
+	workflowFilter, err := newWorkflowFilter(opts.WorkflowNames, opts.IncludeWorkflows, opts.ExcludeWorkflows)
+	if err != nil {
+		return nil, err
+	}
+
+	jobFilter, err := newJobFilter(nil, nil, opts.IncludeJobs, opts.ExcludeJobs)
+	if err != nil {
+		return nil, err
+	}
+
+	where, err := ParseExpression(opts.Where)
+	if err != nil {
+		return nil, err
+	}
+
+	pipelineID, err := client.GetPipelineID(ctx, opts.ProjectType, opts.Org, opts.Project, opts.PipelineNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := checkWorkflowsStatus(
+		ctx, client, pipelineID,
+		checkWorkflowStatusOpts{
+			filterWorkflow:      filterWorkflowByName(workflowFilter, where),
+			filterJob:           filterJobByName(jobFilter, where),
+			succeededJobDetails: true,
+			failedJobDetails:    true,
+			pendingJobDetails:   true,
+			concurrency:         opts.Concurrency,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PipelineStatus{
+		ProjectType:    opts.ProjectType,
+		Org:            opts.Org,
+		Project:        opts.Project,
+		PipelineNumber: opts.PipelineNumber,
+		Failed:         summary.Failed,
+		Finished:       summary.Finished,
+		Workflows:      []*WorkflowStatus{},
+	}
+
+	for _, details := range summary.AllWorkflows {
+		workflow := details.Workflow
+		workflowStatus := &WorkflowStatus{
+			ID:     workflow.ID,
+			Name:   workflow.Name,
+			Status: workflow.Status,
+			Failed: details.Failed,
+			URL:    WorkflowURL(opts.ProjectType, opts.Org, opts.Project, opts.PipelineNumber, workflow.ID),
+			Jobs:   []*JobStatus{},
+		}
+		stoppedAt := ""
+		if circle.WorkflowFinished(workflow) {
+			stoppedAt = workflow.StoppedAt
+		}
+		workflowStatus.Duration = formatDuration(now, workflow.CreatedAt, stoppedAt)
+
+		var jobs []*circle.Job
+		jobs = append(jobs, details.SucceededJobs...)
+		jobs = append(jobs, details.FailedJobs...)
+		jobs = append(jobs, details.AllowedFailedJobs...)
+		jobs = append(jobs, details.PendingJobs...)
+		sort.SliceStable(jobs, func(a, b int) bool {
+			return jobs[a].Name < jobs[b].Name
+		})
+
+		for _, job := range jobs {
+			jobStatus := &JobStatus{
+				ID:       job.ID,
+				Name:     job.Name,
+				Type:     job.Type,
+				Status:   job.Status,
+				Failed:   circle.JobFailed(job),
+				Number:   job.JobNumber,
+				Duration: formatDuration(now, job.StartedAt, job.StoppedAt),
+			}
+			if job.JobNumber != 0 {
+				jobStatus.URL = JobURL(opts.ProjectType, opts.Org, opts.Project, opts.PipelineNumber, workflow.ID, job.JobNumber)
+			}
+			workflowStatus.Jobs = append(workflowStatus.Jobs, jobStatus)
+		}
+
+		result.Workflows = append(result.Workflows, workflowStatus)
+	}
+
+	return result, nil
+}
+
+// formatDuration returns time elapsed since start until stop, or now if stop is not set, or an empty string if start is not set.
+func formatDuration(now time.Time, start string, stop string) string {
+	startedAt, err := time.Parse(time.RFC3339, start)
+	if err != nil {
+		return ""
+	}
+	stoppedAt, err := time.Parse(time.RFC3339, stop)
+	if err != nil {
+		stoppedAt = now
+	}
+	return stoppedAt.Sub(startedAt).Round(time.Second).String()
+}
@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+)
+
+func Test_getPipelineStatus(t *testing.T) {
+	m := newMockCircleClient("github", "influxdata", "testproject")
+	m.addPipeline(123, "456")
+	m.addWorkflows("456", []*circle.Workflow{
+		{ID: "456-1", Name: "build", Status: "failing", CreatedAt: "2021-01-01T00:00:00Z"},
+		{ID: "456-2", Name: "docs", Status: "success", CreatedAt: "2021-01-01T00:00:00Z", StoppedAt: "2021-01-01T00:00:45Z"},
+	})
+	m.addJobs("456-1", []*circle.Job{
+		{ID: "456-1-1", Name: "test", Status: "failed", JobNumber: 12, StartedAt: "2021-01-01T00:00:00Z", StoppedAt: "2021-01-01T00:01:00Z"},
+		{ID: "456-1-2", Name: "lint", Status: "running", JobNumber: 13, StartedAt: "2021-01-01T00:01:00Z"},
+		{ID: "456-1-3", Name: "deploy", Status: "blocked"},
+	})
+	m.addJobs("456-2", []*circle.Job{
+		{ID: "456-2-1", Name: "docs", Status: "success", JobNumber: 11, StartedAt: "2021-01-01T00:00:00Z", StoppedAt: "2021-01-01T00:00:40Z"},
+	})
+
+	now := time.Date(2021, 1, 1, 0, 2, 0, 0, time.UTC)
+	status, err := getPipelineStatus(context.Background(), m, StatusOptions{
+		ProjectType:    "github",
+		Org:            "influxdata",
+		Project:        "testproject",
+		PipelineNumber: 123,
+	}, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want, got := true, status.Failed; want != got {
+		t.Errorf("invalid value for Failed; want %v, got %v", want, got)
+	}
+	if want, got := false, status.Finished; want != got {
+		t.Errorf("invalid value for Finished; want %v, got %v", want, got)
+	}
+	if want, got := 2, len(status.Workflows); want != got {
+		t.Fatalf("invalid number of workflows; want %v, got %v", want, got)
+	}
+
+	build := status.Workflows[0]
+	if want, got := "2m0s", build.Duration; want != got {
+		t.Errorf("invalid workflow duration; want %v, got %v", want, got)
+	}
+	if want, got := "https://app.circleci.com/pipelines/github/influxdata/testproject/123/workflows/456-1", build.URL; want != got {
+		t.Errorf("invalid workflow URL; want %v, got %v", want, got)
+	}
+
+	// jobs should be sorted by name
+	for i, want := range []JobStatus{
+		{ID: "456-1-3", Name: "deploy", Status: "blocked"},
+		{ID: "456-1-2", Name: "lint", Status: "running", Number: 13, Duration: "1m0s", URL: "https://app.circleci.com/pipelines/github/influxdata/testproject/123/workflows/456-1/jobs/13"},
+		{ID: "456-1-1", Name: "test", Status: "failed", Failed: true, Number: 12, Duration: "1m0s", URL: "https://app.circleci.com/pipelines/github/influxdata/testproject/123/workflows/456-1/jobs/12"},
+	} {
+		if got := *build.Jobs[i]; want != got {
+			t.Errorf("invalid job %d; want %+v, got %+v", i, want, got)
+		}
+	}
+
+	if want, got := "45s", status.Workflows[1].Duration; want != got {
+		t.Errorf("invalid duration of finished workflow; want %v, got %v", want, got)
+	}
+}
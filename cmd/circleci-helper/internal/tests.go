@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"context"
+	"sort"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+)
+
+// TestFailuresOptions allows passing options for retrieving failed tests of a pipeline.
+type TestFailuresOptions struct {
+	ProjectType      string
+	Org              string
+	Project          string
+	PipelineNumber   int
+	WorkflowNames    []string
+	IncludeWorkflows []string
+	ExcludeWorkflows []string
+	IncludeJobs      []string
+	ExcludeJobs      []string
+}
+
+// TestFailure describes a test that failed in one or more jobs of a pipeline.
+type TestFailure struct {
+	Name        string                   `json:"name"`
+	Classname   string                   `json:"classname"`
+	File        string                   `json:"file,omitempty"`
+	Occurrences []*TestFailureOccurrence `json:"occurrences"`
+}
+
+// TestFailureOccurrence describes a single failure of a test in a job.
+type TestFailureOccurrence struct {
+	Workflow *circle.Workflow `json:"workflow"`
+	Job      *circle.Job      `json:"job"`
+	Message  string           `json:"message,omitempty"`
+}
+
+// TestFailuresResult lists failed tests of a pipeline, tests failing in most jobs first.
+type TestFailuresResult struct {
+	Failures []*TestFailure `json:"failures"`
+	// FailedJobs is the number of failed jobs that test results were retrieved for.
+	FailedJobs int `json:"failed_jobs"`
+	// FailedJobsWithoutTests is the number of failed jobs that did not report any failed tests.
+	FailedJobsWithoutTests int `json:"failed_jobs_without_tests"`
+}
+
+// GetTestFailures retrieves failed tests of all failed jobs matching criteria, grouping failures of the same test
+// across jobs, such as parallel runs or matrix jobs.
+func GetTestFailures(ctx context.Context, logger *zap.Logger, client circle.Client, opts TestFailuresOptions) (*TestFailuresResult, error) {
+	sugar := logger.Sugar()
+
+	workflowFilter, err := newWorkflowFilter(opts.WorkflowNames, opts.IncludeWorkflows, opts.ExcludeWorkflows)
+	if err != nil {
+		return nil, err
+	}
+
+	jobFilter, err := newJobFilter(nil, nil, opts.IncludeJobs, opts.ExcludeJobs)
+	if err != nil {
+		return nil, err
+	}
+
+	pipelineID, err := client.GetPipelineID(ctx, opts.ProjectType, opts.Org, opts.Project, opts.PipelineNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := checkWorkflowsStatus(
+		ctx, client, pipelineID,
+		checkWorkflowStatusOpts{
+			filterWorkflow: filterWorkflowByName(workflowFilter, nil),
+			filterJob:      filterJobByName(jobFilter, nil),
+			// failed jobs of pending workflows also need to be retrieved
+			failedJobDetails:  true,
+			pendingJobDetails: true,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TestFailuresResult{
+		Failures: []*TestFailure{},
+	}
+
+	type testKey struct {
+		classname, name, file string
+	}
+	failures := map[testKey]*TestFailure{}
+
+	for _, workflow := range status.AllWorkflows {
+		for _, job := range workflow.FailedJobs {
+			result.FailedJobs++
+
+			failedTests, err := getFailedTests(ctx, client, opts.ProjectType, opts.Org, opts.Project, job)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(failedTests) == 0 {
+				sugar.Infof("job %s in workflow %s did not report any failed tests", job.Name, workflow.Workflow.Name)
+				result.FailedJobsWithoutTests++
+				continue
+			}
+
+			for _, test := range failedTests {
+				key := testKey{classname: test.Classname, name: test.Name, file: test.File}
+				failure, ok := failures[key]
+				if !ok {
+					failure = &TestFailure{Name: test.Name, Classname: test.Classname, File: test.File}
+					failures[key] = failure
+					result.Failures = append(result.Failures, failure)
+				}
+				failure.Occurrences = append(failure.Occurrences, &TestFailureOccurrence{
+					Workflow: workflow.Workflow,
+					Job:      job,
+					Message:  test.Message,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(result.Failures, func(a, b int) bool {
+		fa, fb := result.Failures[a], result.Failures[b]
+		if len(fa.Occurrences) != len(fb.Occurrences) {
+			return len(fa.Occurrences) > len(fb.Occurrences)
+		}
+		if fa.Classname != fb.Classname {
+			return fa.Classname < fb.Classname
+		}
+		return fa.Name < fb.Name
+	})
+
+	return result, nil
+}
+
+// getFailedTests retrieves tests that failed in a job ; jobs that have not run yet do not have any test results.
+func getFailedTests(ctx context.Context, client circle.Client, projectType string, org string, project string, job *circle.Job) ([]*circle.TestResult, error) {
+	if job.JobNumber == 0 || job.Status == "blocked" {
+		return nil, nil
+	}
+
+	tests, err := client.GetJobTests(ctx, projectType, org, project, job.JobNumber)
+	if err != nil {
+		// check if the error was 404 - if so, assume the job has not stored any test results
+		httpErr, ok := err.(*circle.ClientHTTPError)
+		if ok && httpErr.StatusCode == 404 {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var result []*circle.TestResult
+	for _, test := range tests {
+		if circle.TestFailed(test) {
+			result = append(result, test)
+		}
+	}
+	return result, nil
+}
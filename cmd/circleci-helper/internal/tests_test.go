@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+)
+
+func newMockCircleClientWithTests() *mockCircleClient {
+	m := newMockCircleClient("github", "influxdata", "testproject")
+	m.addPipeline(123, "456")
+	m.addWorkflows("456", []*circle.Workflow{
+		{ID: "456-1", Name: "test", Status: "failed", CreatedAt: "2021-01-01T00:00:00.000Z"},
+	})
+	m.addJobs("456-1", []*circle.Job{
+		{ID: "456-1-1", JobNumber: 1, Name: "test-linux", Status: "failed"},
+		{ID: "456-1-2", JobNumber: 2, Name: "test-darwin", Status: "failed"},
+		{ID: "456-1-3", JobNumber: 3, Name: "lint", Status: "failed"},
+		{ID: "456-1-4", JobNumber: 4, Name: "build", Status: "success"},
+	})
+	m.testsMap[1] = []*circle.TestResult{
+		{Classname: "pkg", Name: "TestA", File: "pkg/a_test.go", Result: "failure", Message: "linux"},
+		{Classname: "pkg", Name: "TestB", File: "pkg/b_test.go", Result: "success"},
+		{Classname: "pkg", Name: "TestC", File: "pkg/c_test.go", Result: "failure", Message: "timeout"},
+	}
+	m.testsMap[2] = []*circle.TestResult{
+		{Classname: "pkg", Name: "TestA", File: "pkg/a_test.go", Result: "failure", Message: "darwin"},
+		{Classname: "pkg", Name: "TestB", File: "pkg/b_test.go", Result: "skipped"},
+	}
+	m.testsMap[4] = []*circle.TestResult{
+		{Classname: "pkg", Name: "TestD", Result: "failure"},
+	}
+	return m
+}
+
+func Test_GetTestFailures(t *testing.T) {
+	m := newMockCircleClientWithTests()
+
+	result, err := GetTestFailures(context.Background(), zap.NewNop(), m, TestFailuresOptions{
+		ProjectType:    "github",
+		Org:            "influxdata",
+		Project:        "testproject",
+		PipelineNumber: 123,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want, got := 3, result.FailedJobs; want != got {
+		t.Errorf("invalid number of failed jobs; want %v, got %v", want, got)
+	}
+	if want, got := 1, result.FailedJobsWithoutTests; want != got {
+		t.Errorf("invalid number of failed jobs without tests; want %v, got %v", want, got)
+	}
+	if want, got := 2, len(result.Failures); want != got {
+		t.Fatalf("invalid number of failed tests; want %v, got %v", want, got)
+	}
+
+	// tests failing in most jobs should be listed first
+	if want, got := "TestA", result.Failures[0].Name; want != got {
+		t.Errorf("invalid first failed test; want %v, got %v", want, got)
+	}
+	if want, got := 2, len(result.Failures[0].Occurrences); want != got {
+		t.Fatalf("invalid number of occurrences; want %v, got %v", want, got)
+	}
+	if want, got := "test-darwin", result.Failures[0].Occurrences[1].Job.Name; want != got {
+		t.Errorf("invalid job of occurrence; want %v, got %v", want, got)
+	}
+	if want, got := "darwin", result.Failures[0].Occurrences[1].Message; want != got {
+		t.Errorf("invalid message of occurrence; want %v, got %v", want, got)
+	}
+	if want, got := "TestC", result.Failures[1].Name; want != got {
+		t.Errorf("invalid second failed test; want %v, got %v", want, got)
+	}
+
+	// job filters should limit jobs that test results are retrieved for
+	result, err = GetTestFailures(context.Background(), zap.NewNop(), m, TestFailuresOptions{
+		ProjectType:    "github",
+		Org:            "influxdata",
+		Project:        "testproject",
+		PipelineNumber: 123,
+		ExcludeJobs:    []string{"test-linux"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := 1, len(result.Failures); want != got {
+		t.Fatalf("invalid number of failed tests; want %v, got %v", want, got)
+	}
+	if want, got := 1, len(result.Failures[0].Occurrences); want != got {
+		t.Errorf("invalid number of occurrences; want %v, got %v", want, got)
+	}
+}
+
+func Test_GetJobFailures_failedTests(t *testing.T) {
+	m := newMockCircleClientWithTests()
+	m.jobDetailsMap[1] = &circle.JobDetails{
+		Steps: []circle.JobStep{
+			{Name: "checkout", Actions: []circle.JobAction{{Name: "checkout", OutputURL: "output-1-1", HasOutput: true}}},
+			{Name: "unit tests", Actions: []circle.JobAction{{Name: "unit tests", Failed: true, OutputURL: "output-1-2", HasOutput: true}}},
+			{Name: "upload", Actions: []circle.JobAction{{Name: "upload", Failed: true, OutputURL: "output-1-3", HasOutput: true}}},
+		},
+	}
+	m.jobOutputMap["output-1-2"] = []circle.JobOutputMessage{{Message: "FAIL pkg"}}
+	m.jobOutputMap["output-1-3"] = []circle.JobOutputMessage{{Message: "upload failed"}}
+
+	workflow := m.workflowsMap["456"][0]
+	job := m.jobsMap["456-1"][0]
+	failures, err := GetJobFailures(context.Background(), m, "github", "influxdata", "testproject", workflow, job, JobOutputOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := 2, len(failures); want != got {
+		t.Fatalf("invalid number of failures; want %v, got %v", want, got)
+	}
+
+	// failed tests are only listed once per job
+	if want, got := 2, len(failures[0].FailedTests); want != got {
+		t.Errorf("invalid number of failed tests; want %v, got %v", want, got)
+	}
+	if want, got := 0, len(failures[1].FailedTests); want != got {
+		t.Errorf("invalid number of failed tests; want %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// TriggerPipelineOptions allows passing options for triggering a pipeline.
+type TriggerPipelineOptions struct {
+	ProjectType string
+	Org         string
+	Project     string
+	// Branch or Tag to trigger the pipeline for, only one of them may be set ; default branch of the project is used if neither is set.
+	Branch     string
+	Tag        string
+	Parameters map[string]interface{}
+}
+
+// TriggerPipeline triggers a new pipeline with specified parameters, returning the new pipeline.
+func TriggerPipeline(ctx context.Context, logger *zap.Logger, client circle.Client, opts TriggerPipelineOptions) (*circle.Pipeline, error) {
+	if opts.Branch != "" && opts.Tag != "" {
+		return nil, fmt.Errorf("only one of branch and tag can be specified")
+	}
+
+	pipeline, err := client.TriggerPipeline(ctx, opts.ProjectType, opts.Org, opts.Project, circle.TriggerPipelineOptions{
+		Branch:     opts.Branch,
+		Tag:        opts.Tag,
+		Parameters: opts.Parameters,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Sugar().Infof("triggered pipeline %d (id: %s, state: %s)", pipeline.Number, pipeline.ID, pipeline.State)
+	return pipeline, nil
+}
+
+// checkPipelineErrored returns an error if the pipeline has errored, such as because of invalid configuration,
+// in which case it will never have any workflows.
+func checkPipelineErrored(ctx context.Context, client circle.Client, projectType string, org string, project string, pipelineNumber int) error {
+	pipeline, err := client.GetPipeline(ctx, projectType, org, project, pipelineNumber)
+	if err != nil {
+		return err
+	}
+	if pipeline.State != "errored" {
+		return nil
+	}
+
+	var messages []string
+	for _, pipelineError := range pipeline.Errors {
+		messages = append(messages, fmt.Sprintf("%s: %s", pipelineError.Type, pipelineError.Message))
+	}
+	if len(messages) == 0 {
+		return fmt.Errorf("pipeline %d has errored", pipelineNumber)
+	}
+	return fmt.Errorf("pipeline %d has errored: %s", pipelineNumber, strings.Join(messages, "; "))
+}
+
+// ParsePipelineParameters parses parameters specified as name=value, where values true and false are booleans,
+// values that are integers are numbers and all other values are strings.
+func ParsePipelineParameters(values []string) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for _, value := range values {
+		name, text, ok := strings.Cut(value, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid parameter %q, must be specified as name=value", value)
+		}
+
+		if text == "true" || text == "false" {
+			result[name] = text == "true"
+		} else if i, err := strconv.Atoi(text); err == nil {
+			result[name] = i
+		} else {
+			result[name] = text
+		}
+	}
+	return result, nil
+}
+
+// LoadPipelineParameters loads parameters from a YAML or JSON file containing a map of parameter names to values.
+func LoadPipelineParameters(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("unable to parse parameters file %s: %w", path, err)
+	}
+
+	for name, value := range result {
+		switch value.(type) {
+		case string, bool, int, float64:
+		default:
+			return nil, fmt.Errorf("parameter %q in %s must be a string, boolean or number", name, path)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+	"go.uber.org/zap"
+)
+
+func Test_ParsePipelineParameters(t *testing.T) {
+	result, err := ParsePipelineParameters([]string{"enabled=true", "count=3", "name=test", "version=1.2", "empty=", "equals=a=b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"enabled": true,
+		"count":   3,
+		"name":    "test",
+		"version": "1.2",
+		"empty":   "",
+		"equals":  "a=b",
+	}
+	if !reflect.DeepEqual(want, result) {
+		t.Errorf("invalid parameters; want %v, got %v", want, result)
+	}
+
+	for _, value := range []string{"name", "=value"} {
+		if _, err := ParsePipelineParameters([]string{value}); err == nil {
+			t.Errorf("expected an error for %q", value)
+		}
+	}
+}
+
+func Test_LoadPipelineParameters(t *testing.T) {
+	dir := t.TempDir()
+
+	yamlPath := filepath.Join(dir, "params.yml")
+	if err := os.WriteFile(yamlPath, []byte("enabled: true\ncount: 3\nname: test\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	result, err := LoadPipelineParameters(yamlPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := map[string]interface{}{"enabled": true, "count": 3, "name": "test"}; !reflect.DeepEqual(want, result) {
+		t.Errorf("invalid parameters; want %v, got %v", want, result)
+	}
+
+	jsonPath := filepath.Join(dir, "params.json")
+	if err := os.WriteFile(jsonPath, []byte(`{"enabled": false, "name": "test"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	result, err = LoadPipelineParameters(jsonPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := map[string]interface{}{"enabled": false, "name": "test"}; !reflect.DeepEqual(want, result) {
+		t.Errorf("invalid parameters; want %v, got %v", want, result)
+	}
+
+	nestedPath := filepath.Join(dir, "nested.yml")
+	if err := os.WriteFile(nestedPath, []byte("nested:\n  value: 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPipelineParameters(nestedPath); err == nil {
+		t.Errorf("expected an error for nested parameters")
+	}
+}
+
+func Test_TriggerPipeline(t *testing.T) {
+	m := newMockCircleClient("github", "influxdata", "testproject")
+
+	opts := TriggerPipelineOptions{
+		ProjectType: "github",
+		Org:         "influxdata",
+		Project:     "testproject",
+		Branch:      "main",
+		Tag:         "v1.0.0",
+	}
+	if _, err := TriggerPipeline(context.Background(), zap.NewNop(), m, opts); err == nil {
+		t.Errorf("expected an error when both branch and tag are specified")
+	}
+
+	opts.Tag = ""
+	opts.Parameters = map[string]interface{}{"enabled": true}
+	pipeline, err := TriggerPipeline(context.Background(), zap.NewNop(), m, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := 1, len(m.triggered); want != got {
+		t.Fatalf("invalid number of triggered pipelines; want %v, got %v", want, got)
+	}
+	if want, got := (circle.TriggerPipelineOptions{Branch: "main", Parameters: opts.Parameters}), m.triggered[0]; !reflect.DeepEqual(want, got) {
+		t.Errorf("invalid trigger options; want %+v, got %+v", want, got)
+	}
+
+	// waiting for a new pipeline should not finish until it has workflows
+	polls := 0
+	result, err := WaitForJobs(context.Background(), zap.NewNop(), m, WaitForJobsOptions{
+		ProjectType:      "github",
+		Org:              "influxdata",
+		Project:          "testproject",
+		PipelineNumber:   pipeline.Number,
+		RequireWorkflows: true,
+		WaitDuration:     NewWaitForJobsDuration(time.Millisecond),
+		OnEvent: func(event Event) {
+			if _, ok := event.(PollCompleted); ok {
+				polls++
+				if polls == 2 {
+					m.addWorkflows(pipeline.ID, []*circle.Workflow{
+						{ID: "triggered-1", Name: "build", Status: "success", CreatedAt: "2021-01-01T00:00:00.000Z"},
+					})
+				}
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := 3, polls; want != got {
+		t.Errorf("invalid number of checks; want %v, got %v", want, got)
+	}
+	if want, got := 1, len(result.SucceededWorkflows); want != got {
+		t.Errorf("invalid number of succeeded workflows; want %v, got %v", want, got)
+	}
+}
+
+func Test_WaitForJobs_erroredPipeline(t *testing.T) {
+	m := newMockCircleClient("github", "influxdata", "testproject")
+	m.addPipeline(123, "456")
+	m.addWorkflows("456", []*circle.Workflow{})
+	m.pipelineMap[123] = &circle.Pipeline{
+		ID:     "456",
+		Number: 123,
+		State:  "errored",
+		Errors: []circle.PipelineError{{Type: "config", Message: "invalid configuration"}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := WaitForJobs(ctx, zap.NewNop(), m, WaitForJobsOptions{
+		ProjectType:      "github",
+		Org:              "influxdata",
+		Project:          "testproject",
+		PipelineNumber:   123,
+		RequireWorkflows: true,
+		WaitDuration:     NewWaitForJobsDuration(time.Millisecond),
+	})
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	if want, got := "pipeline 123 has errored: config: invalid configuration", err.Error(); want != got {
+		t.Errorf("invalid error; want %q, got %q", want, got)
+	}
+}
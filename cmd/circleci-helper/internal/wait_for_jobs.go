@@ -2,7 +2,6 @@ package internal
 
 import (
 	"context"
-	"math"
 	"time"
 
 	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
@@ -18,88 +17,184 @@ type WaitForJobsOptions struct {
 	WorkflowNames            []string
 	ExcludeJobNames          []string
 	JobPrefixes              []string
+	IncludeWorkflows         []string
+	ExcludeWorkflows         []string
+	IncludeJobs              []string
+	ExcludeJobs              []string
 	FailOnError              bool
 	GetSucceededWorkflowJobs bool
 	GetFailedWorkflowJobs    bool
 	GetPendingWorkflowJobs   bool
 	WaitDuration             *WaitForJobsDuration
+	// RetryFailed is the maximum number of times failed workflows are rerun from failed jobs.
+	RetryFailed int
+	// OnHold is the policy for approval jobs that are on hold - one of OnHoldWait (default), OnHoldIgnore, OnHoldFail or OnHoldApprove.
+	OnHold string
+	// UpstreamOf limits waiting to jobs that the job with this name depends on ; workflows that do not contain it are ignored.
+	UpstreamOf string
+	// Where is an expression that selects workflows and jobs, see Expression for details.
+	Where string
+	// AllowFailure lists patterns of jobs that are waited for and reported, but are allowed to fail.
+	AllowFailure []string
+	// MatrixJobs lists patterns of matrix jobs, such as test-<< matrix.go >>, whose jobs are reported as a single line.
+	MatrixJobs []string
+	// InferMatrixJobs groups jobs whose names differ in two or more dash separated parts, covering all combinations
+	// of their values, as matrix jobs.
+	InferMatrixJobs bool
+	// Verbose logs status of all workflows and jobs on every check, instead of only logging changes.
+	Verbose bool
+	// HeartbeatInterval is how often a summary is logged when nothing has changed, DefaultHeartbeatInterval if not set.
+	HeartbeatInterval time.Duration
+	// RequireWorkflows keeps waiting until at least one workflow matches criteria, such as for pipelines that were just triggered.
+	RequireWorkflows bool
+	// OnEvent, if set, is called with events reporting progress, such as workflows and jobs changing their status.
+	OnEvent func(event Event)
+	// Concurrency is the number of workflows whose jobs are retrieved in parallel, DefaultConcurrency if not set.
+	Concurrency int
 }
 
 // WaitForJobs waits for all jobs matching criteria to finish, ignoring their results.
+// Progress is logged and, if OnEvent is set, also reported as events.
 func WaitForJobs(ctx context.Context, logger *zap.Logger, client circle.Client, opts WaitForJobsOptions) (*WorkflowsSummary, error) {
-	sugar := logger.Sugar()
+	progress := newProgressLogger(logger, opts.Verbose, opts.HeartbeatInterval)
+	emit := emitEvents(progress.handleEvent, opts.OnEvent)
+
+	result, err := waitForJobs(ctx, logger, client, opts, emit)
+	emit(WaitFinished{Result: result, Err: err})
+	return result, err
+}
+
+func waitForJobs(ctx context.Context, logger *zap.Logger, client circle.Client, opts WaitForJobsOptions, emit func(event Event)) (*WorkflowsSummary, error) {
+	if err := validateOnHoldPolicy(opts.OnHold); err != nil {
+		return nil, err
+	}
+
+	workflowFilter, err := newWorkflowFilter(opts.WorkflowNames, opts.IncludeWorkflows, opts.ExcludeWorkflows)
+	if err != nil {
+		return nil, err
+	}
+
+	jobFilter, err := newJobFilter(opts.ExcludeJobNames, opts.JobPrefixes, opts.IncludeJobs, opts.ExcludeJobs)
+	if err != nil {
+		return nil, err
+	}
+
+	where, err := ParseExpression(opts.Where)
+	if err != nil {
+		return nil, err
+	}
+
+	var allowFailure func(workflow *circle.Workflow, job *circle.Job) bool
+	if len(opts.AllowFailure) > 0 {
+		allowFailureFilter, err := NewNameFilter(opts.AllowFailure, nil)
+		if err != nil {
+			return nil, err
+		}
+		allowFailure = filterJobByName(allowFailureFilter, nil)
+	}
+
+	var matrix *MatrixGrouper
+	if len(opts.MatrixJobs) > 0 || opts.InferMatrixJobs {
+		matrix, err = NewMatrixGrouper(opts.MatrixJobs, opts.InferMatrixJobs)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	pipelineID, err := client.GetPipelineID(ctx, opts.ProjectType, opts.Org, opts.Project, opts.PipelineNumber)
 	if err != nil {
 		return nil, err
 	}
 
+	approvals := newApprovalHandler(opts.OnHold)
+	tracker := newEventTracker()
+
+	var retrier *workflowRetrier
+	if opts.RetryFailed > 0 {
+		retrier = newWorkflowRetrier(opts.RetryFailed)
+	}
+
 	// loop forever, timeout is handled by the context ; any API requests to CircleCI
 	// after timeout will fail and the loop will exit with an error
 	for {
 		result, err := checkWorkflowsStatus(
 			ctx, client, pipelineID,
 			checkWorkflowStatusOpts{
-				filterWorkflow:    filterWorkflowWrapper(opts.WorkflowNames),
-				filterJob:         filterJobWrapper(opts.ExcludeJobNames, opts.JobPrefixes),
+				filterWorkflow:    filterWorkflowByName(workflowFilter, where),
+				filterJob:         filterJobByName(jobFilter, where),
 				pendingJobDetails: true,
+				upstreamOf:        opts.UpstreamOf,
+				allowFailure:      allowFailure,
+				// details of failed and succeeded jobs are needed to report jobs that passed after a retry
+				// or counts of matrix jobs, and failed jobs are needed to check if failed workflows only have jobs allowed to fail
+				failedJobDetails:    retrier != nil || allowFailure != nil || matrix != nil || opts.GetFailedWorkflowJobs,
+				succeededJobDetails: retrier != nil || matrix != nil || opts.GetSucceededWorkflowJobs,
+				concurrency:         opts.Concurrency,
 			},
 		)
 
 		if err != nil {
+			emit(PollError{Err: err})
 			return nil, err
 		}
 
+		// pipelines that have errored never get any workflows, so fail instead of waiting for them until timing out
+		if opts.RequireWorkflows && len(result.AllWorkflows) == 0 {
+			if err := checkPipelineErrored(ctx, client, opts.ProjectType, opts.Org, opts.Project, opts.PipelineNumber); err != nil {
+				emit(PollError{Err: err})
+				return nil, err
+			}
+		}
+
 		// assume finished is true if workflows matched unless at least one of them is still pending
 		// if not all of the reported workflows were returned by filters, assume it is not finished and use an empty result
-		if len(result.AllWorkflows) < len(opts.WorkflowNames) {
+		if len(result.AllWorkflows) < len(opts.WorkflowNames) || (opts.RequireWorkflows && len(result.AllWorkflows) == 0) {
 			result = &WorkflowsSummary{}
 		}
 
-		// count number of pending jobs across all workflows
-		pendingJobCount := 0
+		if err := approvals.handleApprovals(ctx, logger, client, result); err != nil {
+			emit(PollError{Err: err})
+			return nil, err
+		}
 
-		// report all workflows - starting with successful ones
-		for _, workflowDetails := range result.SucceededWorkflows {
-			sugar.Infof("workflow %s finished (status: %s)", workflowDetails.Workflow.Name, workflowDetails.Workflow.Status)
+		if retrier != nil {
+			if err := retrier.retryFailedWorkflows(ctx, logger, client, result); err != nil {
+				emit(PollError{Err: err})
+				return nil, err
+			}
 		}
 
-		for _, workflow := range result.FailedWorkflows {
-			sugar.Warnf("workflow %s failed (status: %s)", workflow.Workflow.Name, workflow.Workflow.Status)
+		for _, details := range result.AllWorkflows {
+			details.MatrixJobs = matrix.GroupJobs(details)
 		}
 
+		// count number of pending jobs across all workflows
+		pendingJobCount := 0
 		for _, details := range result.PendingWorkflows {
-			sugar.Infof("workflow %s has not finished yet (status: %s)", details.Workflow.Name, details.Workflow.Status)
-			for _, job := range details.SucceededJobs {
-				sugar.Infof("  - job %s finished (status: %s)", job.Name, job.Status)
-			}
-			for _, job := range details.PendingJobs {
-				sugar.Infof("  - job %s in progress (status: %s)", job.Name, job.Status)
-			}
-			for _, job := range details.FailedJobs {
-				sugar.Warnf("  - job %s failed (status: %s)", job.Name, job.Status)
-			}
 			pendingJobCount += len(details.PendingJobs)
 		}
 
-		// if everything has finished already, simply report that and return
+		for _, event := range tracker.changes(result) {
+			emit(event)
+		}
+
+		// if everything has finished already, simply return the result
 		if result.Finished {
-			if result.Failed {
-				sugar.Warnf("all workflows finished - failed")
-			} else {
-				sugar.Infof("all workflows finished - successfully")
+			if retrier != nil {
+				result.PassedAfterRetryJobs = retrier.passedAfterRetry(result)
 			}
+			emit(PollCompleted{Result: result})
 			return result, nil
 		}
 
 		if result.Failed && opts.FailOnError {
-			sugar.Warnf("one or workflows has failed and should fail on error - exiting")
+			emit(PollCompleted{Result: result})
 			return result, nil
 		}
 
 		// if one more workflows have not finished, wait and try again
 		duration := opts.WaitDuration.GetDuration(pendingJobCount)
-		sugar.Infof("Not all workflows / jobs have finished, waiting for %g seconds", math.Round(duration.Seconds()))
+		emit(PollCompleted{Result: result, NextPoll: duration})
 		time.Sleep(duration)
 	}
 }
@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// outcomes reported by commands to notifiers
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeTimeout = "timeout"
+	OutcomeError   = "error"
+)
+
+// WebhookSignatureHeader is the name of the header that contains HMAC-SHA256 signature of the payload.
+const WebhookSignatureHeader = "X-Circleci-Helper-Signature-256"
+
+// WebhookOptions allows configuring outbound webhook notifications.
+type WebhookOptions struct {
+	URLs []string
+	// Secret is used to sign payloads with HMAC-SHA256 ; payloads are not signed if it is empty.
+	Secret string
+	// Outcomes limits outcomes to notify about ; all outcomes are sent if it is empty.
+	Outcomes []string
+	// Retries is the number of additional attempts made when sending a payload fails.
+	Retries int
+	// Timeout is the timeout for a single attempt to send a payload.
+	Timeout time.Duration
+	// RetryDelay is the delay before the first retry, doubled for each subsequent retry.
+	RetryDelay time.Duration
+}
+
+// WebhookPayload describes JSON document sent to webhook URLs.
+type WebhookPayload struct {
+	Command        string      `json:"command"`
+	Outcome        string      `json:"outcome"`
+	ProjectType    string      `json:"project_type"`
+	Org            string      `json:"org"`
+	Project        string      `json:"project"`
+	PipelineNumber int         `json:"pipeline_number"`
+	Timestamp      time.Time   `json:"timestamp"`
+	Error          string      `json:"error,omitempty"`
+	Result         interface{} `json:"result,omitempty"`
+}
+
+// WebhookNotifier sends signed JSON payloads to one or more URLs.
+type WebhookNotifier struct {
+	logger *zap.Logger
+	opts   WebhookOptions
+}
+
+// NewWebhookNotifier creates a new instance of WebhookNotifier.
+func NewWebhookNotifier(logger *zap.Logger, opts WebhookOptions) *WebhookNotifier {
+	return &WebhookNotifier{
+		logger: logger,
+		opts:   opts,
+	}
+}
+
+// ShouldNotify returns whether specified outcome should be sent to webhooks.
+func (n *WebhookNotifier) ShouldNotify(outcome string) bool {
+	if len(n.opts.URLs) == 0 {
+		return false
+	}
+
+	if len(n.opts.Outcomes) == 0 {
+		return true
+	}
+
+	for _, o := range n.opts.Outcomes {
+		if o == outcome || o == "always" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SignWebhookPayload returns value of the signature header for specified body and secret.
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notify sends the payload to all URLs, if its outcome should be reported, retrying failed attempts.
+func (n *WebhookNotifier) Notify(ctx context.Context, payload *WebhookPayload) error {
+	if !n.ShouldNotify(payload.Outcome) {
+		return nil
+	}
+
+	if payload.Timestamp.IsZero() {
+		payload.Timestamp = time.Now().UTC()
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, webhookURL := range n.opts.URLs {
+		if err := n.send(ctx, webhookURL, body); err != nil {
+			errs = append(errs, fmt.Errorf("unable to send webhook to %s: %w", webhookURL, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// send posts body to a single URL, retrying on network errors and 5xx / 429 responses.
+func (n *WebhookNotifier) send(ctx context.Context, webhookURL string, body []byte) error {
+	delay := n.opts.RetryDelay
+	var err error
+	for attempt := 0; attempt <= n.opts.Retries; attempt++ {
+		if attempt > 0 {
+			n.logger.Sugar().Warnf("sending webhook failed, retrying in %v: %v", delay, err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		var retry bool
+		retry, err = n.sendOnce(ctx, webhookURL, body)
+		if err == nil || !retry {
+			return err
+		}
+	}
+
+	return err
+}
+
+// sendOnce performs a single attempt to send the body, returning whether it makes sense to retry on error.
+func (n *WebhookNotifier) sendOnce(ctx context.Context, webhookURL string, body []byte) (bool, error) {
+	if n.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.opts.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "circleci-helper")
+	if n.opts.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(n.opts.Secret, body))
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		retry := res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("invalid HTTP response code: %d", res.StatusCode)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func Test_WebhookNotifier(t *testing.T) {
+	var attempts int32
+	var received WebhookPayload
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// fail first attempt to validate retries
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		if want, got := SignWebhookPayload("secret", body), r.Header.Get(WebhookSignatureHeader); want != got {
+			t.Errorf("invalid signature; want %v, got %v", want, got)
+		}
+		signature = r.Header.Get(WebhookSignatureHeader)
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("unable to decode payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	n := NewWebhookNotifier(zap.NewNop(), WebhookOptions{
+		URLs:     []string{server.URL},
+		Secret:   "secret",
+		Outcomes: []string{OutcomeFailure},
+		Retries:  2,
+	})
+
+	// outcomes not configured should not be sent
+	if err := n.Notify(context.Background(), &WebhookPayload{Outcome: OutcomeSuccess}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := int32(0), atomic.LoadInt32(&attempts); want != got {
+		t.Errorf("invalid number of attempts; want %v, got %v", want, got)
+	}
+
+	err := n.Notify(context.Background(), &WebhookPayload{
+		Command:        "wait-for-jobs",
+		Outcome:        OutcomeFailure,
+		PipelineNumber: 123,
+		Result:         &WorkflowsSummary{Failed: true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want, got := int32(2), atomic.LoadInt32(&attempts); want != got {
+		t.Errorf("invalid number of attempts; want %v, got %v", want, got)
+	}
+	if signature == "" {
+		t.Errorf("signature header was not sent")
+	}
+	if want, got := 123, received.PipelineNumber; want != got {
+		t.Errorf("invalid pipeline number; want %v, got %v", want, got)
+	}
+	if want, got := OutcomeFailure, received.Outcome; want != got {
+		t.Errorf("invalid outcome; want %v, got %v", want, got)
+	}
+}
+
+func Test_WebhookNotifier_noRetryOnClientError(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	n := NewWebhookNotifier(zap.NewNop(), WebhookOptions{
+		URLs:    []string{server.URL},
+		Retries: 3,
+	})
+
+	if err := n.Notify(context.Background(), &WebhookPayload{Outcome: OutcomeSuccess}); err == nil {
+		t.Errorf("expected an error")
+	}
+	if want, got := int32(1), atomic.LoadInt32(&attempts); want != got {
+		t.Errorf("invalid number of attempts; want %v, got %v", want, got)
+	}
+}
@@ -8,29 +8,36 @@ import (
 
 // WorkflowDetails provides information on CircleCI workflows that have not yet finished with details on individual job statuses.
 type WorkflowDetails struct {
-	Workflow      *circle.Workflow
-	Failed        bool
-	SucceededJobs []*circle.Job
-	FailedJobs    []*circle.Job
-	PendingJobs   []*circle.Job
+	Workflow      *circle.Workflow `json:"workflow"`
+	Failed        bool             `json:"failed"`
+	SucceededJobs []*circle.Job    `json:"succeeded_jobs"`
+	FailedJobs    []*circle.Job    `json:"failed_jobs"`
+	PendingJobs   []*circle.Job    `json:"pending_jobs"`
+	// AllowedFailedJobs lists failed jobs that are allowed to fail and do not cause the workflow to be considered failed.
+	AllowedFailedJobs []*circle.Job `json:"allowed_failed_jobs,omitempty"`
+	// MatrixJobs summarizes jobs expanded from matrix jobs, if grouping of matrix jobs was requested.
+	MatrixJobs []*MatrixJob `json:"matrix_jobs,omitempty"`
 }
 
 // WorkflowsSummary provides summary on all workflows matching pattern and groups them into categories for easier reporting.
 type WorkflowsSummary struct {
-	Failed             bool
-	Finished           bool
-	AllWorkflows       []*WorkflowDetails
-	SucceededWorkflows []*WorkflowDetails
-	FailedWorkflows    []*WorkflowDetails
-	PendingWorkflows   []*WorkflowDetails
+	Failed             bool               `json:"failed"`
+	Finished           bool               `json:"finished"`
+	AllWorkflows       []*WorkflowDetails `json:"all_workflows"`
+	SucceededWorkflows []*WorkflowDetails `json:"succeeded_workflows"`
+	FailedWorkflows    []*WorkflowDetails `json:"failed_workflows"`
+	PendingWorkflows   []*WorkflowDetails `json:"pending_workflows"`
+	// PassedAfterRetryJobs lists jobs that failed, but passed after their workflow was rerun.
+	PassedAfterRetryJobs []*RetriedJob `json:"passed_after_retry_jobs,omitempty"`
 }
 
 // prepareWorkflowDetails prepares WorkflowDetails for a workflow, listing and filtering jobs and grouping them by status.
+// It returns nil if jobs are limited to upstream jobs of a job that the workflow does not contain.
 func prepareWorkflowDetails(
 	ctx context.Context,
 	client circle.Client,
 	workflow *circle.Workflow,
-	filterJob func(job *circle.Job) bool,
+	opts checkWorkflowStatusOpts,
 	listJobs bool,
 ) (*WorkflowDetails, error) {
 	workflowDetails := &WorkflowDetails{
@@ -44,15 +51,37 @@ func prepareWorkflowDetails(
 			return nil, err
 		}
 
+		// if limiting to upstream jobs of a job in this workflow, only keep jobs it depends on
+		var upstream map[string]bool
+		if opts.upstreamOf != "" {
+			for _, job := range jobs {
+				if job.Name == opts.upstreamOf {
+					upstream = upstreamJobIDs(jobs, job)
+					break
+				}
+			}
+			// the workflow does not contain the job, so none of its jobs are upstream jobs of it
+			if upstream == nil {
+				return nil, nil
+			}
+		}
+
 		for _, job := range jobs {
+			if upstream != nil && !upstream[job.ID] {
+				continue
+			}
+
 			// if filter was provided and the job does not match the filter, skip it
-			if filterJob != nil && !filterJob(job) {
+			if opts.filterJob != nil && !opts.filterJob(workflow, job) {
 				continue
 			}
 
 			if !circle.JobFinished(job) {
 				// if the job has not finished yet, store it in the list of pending jobs
 				workflowDetails.PendingJobs = append(workflowDetails.PendingJobs, job)
+			} else if circle.JobFailed(job) && opts.allowFailure != nil && opts.allowFailure(workflow, job) {
+				// if the job has failed, but is allowed to fail, store it separately
+				workflowDetails.AllowedFailedJobs = append(workflowDetails.AllowedFailedJobs, job)
 			} else if circle.JobFailed(job) {
 				// if the job has failed, store it as a failed job
 				workflowDetails.FailedJobs = append(workflowDetails.FailedJobs, job)
@@ -91,10 +120,18 @@ func getLatestWorkflows(
 
 type checkWorkflowStatusOpts struct {
 	filterWorkflow      func(workflow *circle.Workflow) bool
-	filterJob           func(job *circle.Job) bool
+	filterJob           func(workflow *circle.Workflow, job *circle.Job) bool
 	succeededJobDetails bool
 	failedJobDetails    bool
 	pendingJobDetails   bool
+	// allowFailure returns whether a job is allowed to fail without causing its workflow to be considered failed ;
+	// it requires failedJobDetails so that failed workflows can be checked
+	allowFailure func(workflow *circle.Workflow, job *circle.Job) bool
+	// upstreamOf limits jobs to ones that the job with this name depends on ; workflows that do not contain it are
+	// ignored, so jobs of all workflows are listed to find out which ones contain it
+	upstreamOf string
+	// concurrency is the number of workflows whose jobs are retrieved in parallel, DefaultConcurrency if not set
+	concurrency int
 }
 
 // checkWorkflowsStatus generates details for all workflows, filtering workflows and jobs, optionally also retrieving details for all or specific types of workflows / jobs
@@ -111,14 +148,46 @@ func checkWorkflowsStatus(
 		return nil, err
 	}
 
+	// retrieve jobs of all workflows in parallel, then process workflows in order
+	allDetails := make([]*WorkflowDetails, len(workflows))
+	err = forEachConcurrently(ctx, opts.concurrency, len(workflows), func(ctx context.Context, i int) error {
+		workflow := workflows[i]
+		listJobs := opts.pendingJobDetails
+		if circle.WorkflowFinished(workflow) && circle.WorkflowFailed(workflow) {
+			listJobs = opts.failedJobDetails
+		} else if circle.WorkflowFinished(workflow) {
+			listJobs = opts.succeededJobDetails
+		}
+		if opts.upstreamOf != "" {
+			listJobs = true
+		}
+
+		workflowDetails, err := prepareWorkflowDetails(ctx, client, workflow, opts, listJobs)
+		if err != nil {
+			return err
+		}
+		allDetails[i] = workflowDetails
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	result.Finished = true
-	for _, workflow := range workflows {
+	for i, workflow := range workflows {
+		workflowDetails := allDetails[i]
+		if workflowDetails == nil {
+			continue
+		}
 		if circle.WorkflowFinished(workflow) {
 			// if the workflow has finished, store it either as successful or failed
 			if circle.WorkflowFailed(workflow) {
-				workflowDetails, err := prepareWorkflowDetails(ctx, client, workflow, opts.filterJob, opts.failedJobDetails)
-				if err != nil {
-					return nil, err
+				// if the workflow only failed because of jobs that are allowed to fail, consider it successful
+				if workflow.Status == "failed" && len(workflowDetails.FailedJobs) == 0 && len(workflowDetails.AllowedFailedJobs) > 0 {
+					workflowDetails.Failed = false
+					result.SucceededWorkflows = append(result.SucceededWorkflows, workflowDetails)
+					result.AllWorkflows = append(result.AllWorkflows, workflowDetails)
+					continue
 				}
 
 				result.FailedWorkflows = append(result.FailedWorkflows, workflowDetails)
@@ -126,11 +195,6 @@ func checkWorkflowsStatus(
 
 				result.Failed = true
 			} else {
-				workflowDetails, err := prepareWorkflowDetails(ctx, client, workflow, opts.filterJob, opts.succeededJobDetails)
-				if err != nil {
-					return nil, err
-				}
-
 				result.SucceededWorkflows = append(result.SucceededWorkflows, workflowDetails)
 				result.AllWorkflows = append(result.AllWorkflows, workflowDetails)
 
@@ -139,11 +203,6 @@ func checkWorkflowsStatus(
 			continue
 		}
 
-		workflowDetails, err := prepareWorkflowDetails(ctx, client, workflow, opts.filterJob, opts.pendingJobDetails)
-		if err != nil {
-			return nil, err
-		}
-
 		if opts.pendingJobDetails {
 			// if we've retrieved pending job details, assume the workflow has failed if at least one job has already failed and function was asked to retrieve details
 			if len(workflowDetails.PendingJobs) > 0 {
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
@@ -10,27 +11,73 @@ import (
 
 // WorkflowErrorsOptions allows passing options for retrieving status of one or more workflows.
 type WorkflowErrorsOptions struct {
-	ProjectType    string
-	Org            string
-	Project        string
-	PipelineNumber int
-	WorkflowNames  []string
+	ProjectType      string
+	Org              string
+	Project          string
+	PipelineNumber   int
+	WorkflowNames    []string
+	IncludeWorkflows []string
+	ExcludeWorkflows []string
+	// FullOutput reports whole output of failed steps instead of excerpts.
+	FullOutput bool
+	// Excerpt configures how excerpts of output of failed steps are extracted.
+	Excerpt ExcerptOptions
+	// OutputLimits limits whole output of failed steps that is reported with FullOutput.
+	OutputLimits OutputLimits
+	// Nodes limits failures of jobs with parallelism to nodes with these indexes, if set.
+	Nodes []int
+	// Concurrency is the number of workflows and jobs whose details are retrieved in parallel, DefaultConcurrency if not set.
+	Concurrency int
+}
+
+// JobOutputOptions configures how output of failed steps of a job is reported.
+type JobOutputOptions struct {
+	// Excerpt, if set, reports excerpts of output instead of whole output ; excerpts are not affected by Limits.
+	Excerpt *ExcerptExtractor
+	// Limits limits whole output that is reported if Excerpt is not set.
+	Limits OutputLimits
+	// Nodes limits failures of jobs with parallelism to nodes with these indexes, if set.
+	Nodes []int
 }
 
 type WorkflowErrorsFailure struct {
-	Workflow   *circle.Workflow
-	Job        *circle.Job
-	StepName   string
-	ActionName string
-	Messages   string
+	Workflow   *circle.Workflow `json:"workflow"`
+	Job        *circle.Job      `json:"job"`
+	StepName   string           `json:"step_name"`
+	ActionName string           `json:"action_name"`
+	Messages   string           `json:"messages"`
+	// Action describes the failed action, including index of the node it ran on and its exit code.
+	Action *circle.JobAction `json:"action"`
+	// Detectors lists names of detectors that found interesting lines in output, if an excerpt was extracted.
+	Detectors []string `json:"detectors,omitempty"`
+	// OmittedLines is the number of lines of output not included in Messages, if an excerpt was extracted.
+	OmittedLines int `json:"omitted_lines,omitempty"`
+	// OutputTruncated is set if whole output was reported, but it was truncated because of limits.
+	OutputTruncated bool `json:"output_truncated,omitempty"`
+	// FailedTests lists tests that failed in the job, as parsed by CircleCI from stored test results ; as test results
+	// are stored per job, they are only listed for the first failed step of each job.
+	FailedTests []*circle.TestResult `json:"failed_tests,omitempty"`
 }
 
 type WorkflowErrorsResult struct {
-	Failures []*WorkflowErrorsFailure
+	Failures []*WorkflowErrorsFailure `json:"failures"`
 }
 
 // WorkflowErrors retrieves all errors for a workflow
 func WorkflowErrors(ctx context.Context, logger *zap.Logger, client circle.Client, opts WorkflowErrorsOptions) (*WorkflowErrorsResult, error) {
+	workflowFilter, err := newWorkflowFilter(opts.WorkflowNames, opts.IncludeWorkflows, opts.ExcludeWorkflows)
+	if err != nil {
+		return nil, err
+	}
+
+	outputOpts := JobOutputOptions{Limits: opts.OutputLimits, Nodes: opts.Nodes}
+	if !opts.FullOutput {
+		outputOpts.Excerpt, err = NewExcerptExtractor(opts.Excerpt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	pipelineID, err := client.GetPipelineID(ctx, opts.ProjectType, opts.Org, opts.Project, opts.PipelineNumber)
 	if err != nil {
 		return nil, err
@@ -39,11 +86,12 @@ func WorkflowErrors(ctx context.Context, logger *zap.Logger, client circle.Clien
 	status, err := checkWorkflowsStatus(
 		ctx, client, pipelineID,
 		checkWorkflowStatusOpts{
-			filterWorkflow: filterWorkflowWrapper(opts.WorkflowNames),
+			filterWorkflow: filterWorkflowByName(workflowFilter, nil),
 			// retrieve details for all types of jobs
 			succeededJobDetails: true,
 			failedJobDetails:    true,
 			pendingJobDetails:   true,
+			concurrency:         opts.Concurrency,
 		},
 	)
 
@@ -61,52 +109,180 @@ func WorkflowErrors(ctx context.Context, logger *zap.Logger, client circle.Clien
 		Failures: []*WorkflowErrorsFailure{},
 	}
 
+	type workflowJob struct {
+		workflow *circle.Workflow
+		job      *circle.Job
+	}
+
+	var jobs []workflowJob
 	for _, workflow := range status.AllWorkflows {
-		jobs := append(workflow.FailedJobs, workflow.PendingJobs...)
+		for _, job := range append(workflow.FailedJobs, workflow.PendingJobs...) {
+			jobs = append(jobs, workflowJob{workflow: workflow.Workflow, job: job})
+		}
+	}
 
-		for _, job := range jobs {
-			// ignore jobs that were blocked by other dependencies since they do not have any details to retrieve
-			if job.Status == "blocked" {
-				continue
-			}
+	// retrieve failures of all jobs in parallel, then report them in order of workflows and jobs
+	jobFailures := make([][]*WorkflowErrorsFailure, len(jobs))
+	err = forEachConcurrently(ctx, opts.Concurrency, len(jobs), func(ctx context.Context, i int) error {
+		failures, err := GetJobFailures(ctx, client, opts.ProjectType, opts.Org, opts.Project, jobs[i].workflow, jobs[i].job, outputOpts)
+		if err != nil {
+			return err
+		}
+		jobFailures[i] = failures
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-			details, err := client.GetJobDetails(ctx, opts.ProjectType, opts.Org, opts.Project, job.JobNumber)
-			if err != nil {
-				// check if the error was 404 - if so, assume the job has not yet been run and continue
-				httpErr, ok := err.(*circle.ClientHTTPError)
-				if ok && httpErr.StatusCode == 404 {
-					continue
-				}
+	for _, failures := range jobFailures {
+		result.Failures = append(result.Failures, failures...)
+	}
 
-				// if this was not a 404 error, return the real error
-				return nil, err
-			}
+	return result, nil
+}
+
+// GetJobFailures retrieves output of all failed steps of a job ; jobs that have not run yet do not have any failures.
+// Output is retrieved as a stream, so that only excerpts or output up to the limits are kept in memory.
+func GetJobFailures(
+	ctx context.Context,
+	client circle.Client,
+	projectType string, org string, project string,
+	workflow *circle.Workflow,
+	job *circle.Job,
+	opts JobOutputOptions,
+) ([]*WorkflowErrorsFailure, error) {
+	// ignore jobs that were blocked by other dependencies since they do not have any details to retrieve
+	if job.Status == "blocked" {
+		return nil, nil
+	}
+
+	details, err := client.GetJobDetails(ctx, projectType, org, project, job.JobNumber)
+	if err != nil {
+		// check if the error was 404 - if so, assume the job has not yet been run and continue
+		httpErr, ok := err.(*circle.ClientHTTPError)
+		if ok && httpErr.StatusCode == 404 {
+			return nil, nil
+		}
+
+		// if this was not a 404 error, return the real error
+		return nil, err
+	}
+
+	nodes := map[int]bool{}
+	for _, node := range opts.Nodes {
+		nodes[node] = true
+	}
+
+	var result []*WorkflowErrorsFailure
+	for _, step := range details.Steps {
+		for _, action := range step.Actions {
+			if action.Failed && (len(nodes) == 0 || nodes[action.Index]) {
+				failure := &WorkflowErrorsFailure{
+					Workflow:   workflow,
+					Job:        job,
+					StepName:   step.Name,
+					ActionName: action.Name,
+					Action:     &action,
+				}
 
-			for _, step := range details.Steps {
-				for _, action := range step.Actions {
-					if action.Failed {
-						output, err := client.GetJobActionOutput(ctx, &action)
-						if err != nil {
-							return nil, err
-						}
-
-						var sb strings.Builder
-						for _, line := range output {
-							sb.WriteString(line.Message)
-						}
-
-						result.Failures = append(result.Failures, &WorkflowErrorsFailure{
-							Workflow:   workflow.Workflow,
-							Job:        job,
-							StepName:   step.Name,
-							ActionName: action.Name,
-							Messages:   sb.String(),
-						})
+				if opts.Excerpt != nil {
+					w := opts.Excerpt.NewWriter()
+					if _, err := WriteJobActionOutput(ctx, client, &action, w, OutputLimits{}); err != nil {
+						return nil, err
+					}
+					excerpt := w.Excerpt()
+					failure.Messages = excerpt.Text
+					failure.Detectors = excerpt.Detectors
+					failure.OmittedLines = excerpt.OmittedLines
+				} else {
+					var sb strings.Builder
+					summary, err := WriteJobActionOutput(ctx, client, &action, &sb, opts.Limits)
+					if err != nil {
+						return nil, err
 					}
+					failure.Messages = sb.String()
+					failure.OutputTruncated = summary.Truncated
 				}
+
+				result = append(result, failure)
 			}
 		}
 	}
 
+	if len(result) > 0 {
+		failedTests, err := getFailedTests(ctx, client, projectType, org, project, job)
+		if err != nil {
+			return nil, err
+		}
+		result[0].FailedTests = failedTests
+	}
+
 	return result, nil
 }
+
+// StepFailures groups failures of the same step of a job ; steps of jobs with parallelism have a failure for each failed node.
+type StepFailures struct {
+	Workflow *circle.Workflow         `json:"workflow"`
+	Job      *circle.Job              `json:"job"`
+	StepName string                   `json:"step_name"`
+	Failures []*WorkflowErrorsFailure `json:"failures"`
+}
+
+// GroupFailuresByStep groups failures by workflow, job and step, keeping their order.
+func GroupFailuresByStep(failures []*WorkflowErrorsFailure) []*StepFailures {
+	type stepKey struct {
+		workflowID, jobID, stepName string
+	}
+
+	var result []*StepFailures
+	steps := map[stepKey]*StepFailures{}
+	for _, failure := range failures {
+		key := stepKey{workflowID: failure.Workflow.ID, jobID: failure.Job.ID, stepName: failure.StepName}
+		step, ok := steps[key]
+		if !ok {
+			step = &StepFailures{Workflow: failure.Workflow, Job: failure.Job, StepName: failure.StepName}
+			steps[key] = step
+			result = append(result, step)
+		}
+		step.Failures = append(step.Failures, failure)
+	}
+	return result
+}
+
+// Parallel returns whether the step ran on multiple nodes.
+func (s *StepFailures) Parallel() bool {
+	for _, failure := range s.Failures {
+		if failure.Action != nil && failure.Action.Parallel {
+			return true
+		}
+	}
+	return false
+}
+
+// DescribeNodes describes nodes that the step failed on, such as "nodes 0 (exit code 1), 3 (exit code 2)".
+func (s *StepFailures) DescribeNodes() string {
+	descriptions := make([]string, len(s.Failures))
+	for i, failure := range s.Failures {
+		descriptions[i] = strings.TrimPrefix(DescribeFailedNode(failure.Action), "node ")
+	}
+	if len(descriptions) == 1 {
+		return "node " + descriptions[0]
+	}
+	return "nodes " + strings.Join(descriptions, ", ")
+}
+
+// DescribeFailedNode describes the node that an action failed on, such as "node 3 (exit code 2)".
+func DescribeFailedNode(action *circle.JobAction) string {
+	if action == nil {
+		return "node 0"
+	}
+	switch {
+	case action.ExitCode != nil:
+		return fmt.Sprintf("node %d (exit code %d)", action.Index, *action.ExitCode)
+	case action.Status != "":
+		return fmt.Sprintf("node %d (%s)", action.Index, action.Status)
+	default:
+		return fmt.Sprintf("node %d", action.Index)
+	}
+}
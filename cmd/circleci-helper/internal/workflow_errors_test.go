@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
+)
+
+func newMockCircleClientWithParallelJob() *mockCircleClient {
+	exitCode := func(code int) *int { return &code }
+
+	m := newMockCircleClientWithTests()
+	m.jobDetailsMap[1] = &circle.JobDetails{
+		Steps: []circle.JobStep{
+			{Name: "checkout", Actions: []circle.JobAction{
+				{Name: "checkout", Index: 0, Parallel: true, Status: "success", ExitCode: exitCode(0), OutputURL: "output-0-1", HasOutput: true},
+				{Name: "checkout", Index: 1, Parallel: true, Status: "success", ExitCode: exitCode(0), OutputURL: "output-1-1", HasOutput: true},
+				{Name: "checkout", Index: 2, Parallel: true, Status: "success", ExitCode: exitCode(0), OutputURL: "output-2-1", HasOutput: true},
+			}},
+			{Name: "unit tests", Actions: []circle.JobAction{
+				{Name: "unit tests", Index: 0, Parallel: true, Status: "failed", Failed: true, ExitCode: exitCode(1), OutputURL: "output-0-2", HasOutput: true},
+				{Name: "unit tests", Index: 1, Parallel: true, Status: "success", ExitCode: exitCode(0), OutputURL: "output-1-2", HasOutput: true},
+				{Name: "unit tests", Index: 2, Parallel: true, Status: "timedout", Failed: true, OutputURL: "output-2-2", HasOutput: true},
+			}},
+		},
+	}
+	m.jobOutputMap["output-0-2"] = []circle.JobOutputMessage{{Message: "FAIL node 0"}}
+	m.jobOutputMap["output-2-2"] = []circle.JobOutputMessage{{Message: "Too long with no output"}}
+	return m
+}
+
+func Test_GetJobFailures_parallel(t *testing.T) {
+	m := newMockCircleClientWithParallelJob()
+	workflow := m.workflowsMap["456"][0]
+	job := m.jobsMap["456-1"][0]
+
+	failures, err := GetJobFailures(context.Background(), m, "github", "influxdata", "testproject", workflow, job, JobOutputOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := 2, len(failures); want != got {
+		t.Fatalf("invalid number of failures; want %v, got %v", want, got)
+	}
+	if want, got := 2, failures[1].Action.Index; want != got {
+		t.Errorf("invalid node index; want %v, got %v", want, got)
+	}
+
+	steps := GroupFailuresByStep(failures)
+	if want, got := 1, len(steps); want != got {
+		t.Fatalf("invalid number of steps; want %v, got %v", want, got)
+	}
+	if !steps[0].Parallel() {
+		t.Errorf("expected step to be parallel")
+	}
+	if want, got := "nodes 0 (exit code 1), 2 (timedout)", steps[0].DescribeNodes(); want != got {
+		t.Errorf("invalid description of nodes; want %q, got %q", want, got)
+	}
+
+	// failures can be limited to a single node
+	failures, err = GetJobFailures(context.Background(), m, "github", "influxdata", "testproject", workflow, job, JobOutputOptions{Nodes: []int{2}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := 1, len(failures); want != got {
+		t.Fatalf("invalid number of failures; want %v, got %v", want, got)
+	}
+	if want, got := "Too long with no output", failures[0].Messages; want != got {
+		t.Errorf("invalid messages; want %q, got %q", want, got)
+	}
+	if want, got := "node 2 (timedout)", GroupFailuresByStep(failures)[0].DescribeNodes(); want != got {
+		t.Errorf("invalid description of nodes; want %q, got %q", want, got)
+	}
+}
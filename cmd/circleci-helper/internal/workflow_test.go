@@ -2,7 +2,11 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/influxdata/circleci-helper/cmd/circleci-helper/circle"
@@ -13,10 +17,21 @@ type mockCircleClient struct {
 	org           string
 	project       string
 	pipelineIDMap map[int]string
+	branchMap     map[int]string
+	pipelineMap   map[int]*circle.Pipeline
 	workflowsMap  map[string][]*circle.Workflow
 	jobsMap       map[string][]*circle.Job
 	jobDetailsMap map[int]*circle.JobDetails
 	jobOutputMap  map[string][]circle.JobOutputMessage
+	artifactsMap  map[int][]*circle.Artifact
+	contentsMap   map[string]string
+	testsMap      map[int][]*circle.TestResult
+	canceled      []string
+	rerun         []string
+	rerunOpts     []circle.RerunWorkflowOptions
+	rerunStatus   string
+	approved      []string
+	triggered     []circle.TriggerPipelineOptions
 }
 
 func newMockCircleClient(projectType, org, project string) *mockCircleClient {
@@ -25,10 +40,15 @@ func newMockCircleClient(projectType, org, project string) *mockCircleClient {
 		org:           org,
 		project:       project,
 		pipelineIDMap: map[int]string{},
+		branchMap:     map[int]string{},
+		pipelineMap:   map[int]*circle.Pipeline{},
 		workflowsMap:  map[string][]*circle.Workflow{},
 		jobsMap:       map[string][]*circle.Job{},
 		jobDetailsMap: map[int]*circle.JobDetails{},
 		jobOutputMap:  map[string][]circle.JobOutputMessage{},
+		artifactsMap:  map[int][]*circle.Artifact{},
+		contentsMap:   map[string]string{},
+		testsMap:      map[int][]*circle.TestResult{},
 	}
 }
 
@@ -36,6 +56,11 @@ func (m *mockCircleClient) addPipeline(number int, id string) {
 	m.pipelineIDMap[number] = id
 }
 
+func (m *mockCircleClient) addPipelineForBranch(number int, id string, branch string) {
+	m.pipelineIDMap[number] = id
+	m.branchMap[number] = branch
+}
+
 func (m *mockCircleClient) addWorkflows(pipelineID string, workflows []*circle.Workflow) {
 	m.workflowsMap[pipelineID] = workflows
 }
@@ -55,6 +80,50 @@ func (m *mockCircleClient) GetPipelineID(ctx context.Context, projectType string
 	return res, nil
 }
 
+func (m *mockCircleClient) GetPipeline(ctx context.Context, projectType string, org string, project string, pipelineNumber int) (*circle.Pipeline, error) {
+	id, err := m.GetPipelineID(ctx, projectType, org, project, pipelineNumber)
+	if err != nil {
+		return nil, err
+	}
+	if pipeline, ok := m.pipelineMap[pipelineNumber]; ok {
+		return pipeline, nil
+	}
+	return &circle.Pipeline{ID: id, Number: pipelineNumber, VCS: circle.PipelineVCS{Branch: m.branchMap[pipelineNumber]}}, nil
+}
+
+func (m *mockCircleClient) ListPipelines(ctx context.Context, projectType string, org string, project string, opts circle.ListPipelinesOptions) ([]*circle.Pipeline, error) {
+	if m.projectType != projectType || m.org != org || m.project != project {
+		return nil, fmt.Errorf("invalid project info")
+	}
+	var result []*circle.Pipeline
+	for number, id := range m.pipelineIDMap {
+		if opts.Branch == "" || opts.Branch == m.branchMap[number] {
+			result = append(result, &circle.Pipeline{ID: id, Number: number, VCS: circle.PipelineVCS{Branch: m.branchMap[number]}})
+		}
+	}
+	sort.Slice(result, func(a, b int) bool {
+		return result[a].Number > result[b].Number
+	})
+	if opts.Limit > 0 && len(result) > opts.Limit {
+		result = result[:opts.Limit]
+	}
+	return result, nil
+}
+
+func (m *mockCircleClient) TriggerPipeline(ctx context.Context, projectType string, org string, project string, opts circle.TriggerPipelineOptions) (*circle.Pipeline, error) {
+	if m.projectType != projectType || m.org != org || m.project != project {
+		return nil, fmt.Errorf("invalid project")
+	}
+	m.triggered = append(m.triggered, opts)
+
+	// add a new pipeline, without any workflows yet
+	number := len(m.pipelineIDMap) + 1000
+	id := fmt.Sprintf("triggered-%d", number)
+	m.addPipeline(number, id)
+	m.addWorkflows(id, []*circle.Workflow{})
+	return &circle.Pipeline{ID: id, Number: number, State: "created"}, nil
+}
+
 func (m *mockCircleClient) GetWorkflows(ctx context.Context, pipelineID string) ([]*circle.Workflow, error) {
 	res, ok := m.workflowsMap[pipelineID]
 	if !ok {
@@ -71,6 +140,52 @@ func (m *mockCircleClient) GetWorkflowJobs(ctx context.Context, workflowID strin
 	return res, nil
 }
 
+func (m *mockCircleClient) CancelWorkflow(ctx context.Context, workflowID string) error {
+	m.canceled = append(m.canceled, workflowID)
+	return nil
+}
+
+func (m *mockCircleClient) RerunWorkflow(ctx context.Context, workflowID string, opts circle.RerunWorkflowOptions) (string, error) {
+	m.rerun = append(m.rerun, workflowID)
+	m.rerunOpts = append(m.rerunOpts, opts)
+	for pipelineID, workflows := range m.workflowsMap {
+		for _, workflow := range workflows {
+			if workflow.ID != workflowID {
+				continue
+			}
+
+			// create a newer instance of the workflow with all jobs in rerunStatus
+			newID := fmt.Sprintf("%s-rerun-%d", workflowID, len(m.rerun))
+			m.workflowsMap[pipelineID] = append(workflows, &circle.Workflow{
+				ID:        newID,
+				Name:      workflow.Name,
+				Status:    m.rerunStatus,
+				CreatedAt: fmt.Sprintf("2099-01-01T00:00:%02d.000Z", len(m.rerun)),
+			})
+			var jobs []*circle.Job
+			for _, job := range m.jobsMap[workflowID] {
+				jobs = append(jobs, &circle.Job{ID: job.ID + "-rerun", Name: job.Name, Status: m.rerunStatus})
+			}
+			m.jobsMap[newID] = jobs
+			return newID, nil
+		}
+	}
+	return "", fmt.Errorf("invalid workflowID")
+}
+
+func (m *mockCircleClient) ApproveJob(ctx context.Context, workflowID string, approvalRequestID string) error {
+	m.approved = append(m.approved, approvalRequestID)
+	// mark the approval job as approved and its dependent jobs as running
+	for _, job := range m.jobsMap[workflowID] {
+		if job.ApprovalRequestID == approvalRequestID {
+			job.Status = "success"
+		} else if job.Status == "blocked" {
+			job.Status = "success"
+		}
+	}
+	return nil
+}
+
 func (m *mockCircleClient) GetJobDetails(ctx context.Context, projectType string, org string, project string, jobNumber int) (*circle.JobDetails, error) {
 	if m.projectType != projectType || m.org != org || m.project != project {
 		return nil, fmt.Errorf("invalid project info")
@@ -83,6 +198,9 @@ func (m *mockCircleClient) GetJobDetails(ctx context.Context, projectType string
 }
 
 func (m *mockCircleClient) GetJobActionOutput(ctx context.Context, action *circle.JobAction) ([]circle.JobOutputMessage, error) {
+	if !circle.JobActionHasOutput(action) {
+		return nil, nil
+	}
 	res, ok := m.jobOutputMap[action.OutputURL]
 	if !ok {
 		return nil, fmt.Errorf("invalid action's OutputURL")
@@ -90,6 +208,47 @@ func (m *mockCircleClient) GetJobActionOutput(ctx context.Context, action *circl
 	return res, nil
 }
 
+func (m *mockCircleClient) StreamJobActionOutput(ctx context.Context, action *circle.JobAction, fn func(message circle.JobOutputMessage) error) error {
+	if !circle.JobActionHasOutput(action) {
+		return nil
+	}
+	res, ok := m.jobOutputMap[action.OutputURL]
+	if !ok {
+		return fmt.Errorf("invalid action's OutputURL")
+	}
+	for _, message := range res {
+		if err := fn(message); err != nil {
+			if errors.Is(err, circle.ErrStopOutput) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mockCircleClient) GetJobArtifacts(ctx context.Context, projectType string, org string, project string, jobNumber int) ([]*circle.Artifact, error) {
+	if m.projectType != projectType || m.org != org || m.project != project {
+		return nil, fmt.Errorf("invalid project info")
+	}
+	return m.artifactsMap[jobNumber], nil
+}
+
+func (m *mockCircleClient) GetJobTests(ctx context.Context, projectType string, org string, project string, jobNumber int) ([]*circle.TestResult, error) {
+	if m.projectType != projectType || m.org != org || m.project != project {
+		return nil, fmt.Errorf("invalid project info")
+	}
+	return m.testsMap[jobNumber], nil
+}
+
+func (m *mockCircleClient) DownloadArtifact(ctx context.Context, artifact *circle.Artifact) (io.ReadCloser, int64, error) {
+	res, ok := m.contentsMap[artifact.URL]
+	if !ok {
+		return nil, 0, &circle.ClientHTTPError{StatusCode: 404}
+	}
+	return io.NopCloser(strings.NewReader(res)), int64(len(res)), nil
+}
+
 func newMockCircleClientWithData(workflow1Status, workflow2Status, job1Status, job2Status string) *mockCircleClient {
 	m := newMockCircleClient("github", "influxdata", "testproject")
 	m.addPipeline(123, "456")
@@ -322,3 +481,63 @@ func Test_checkWorkflowsStatus(t *testing.T) {
 		})
 	}
 }
+
+func Test_checkWorkflowsStatus_allowFailure(t *testing.T) {
+	for _, test := range []struct {
+		name                    string
+		allowFailure            []string
+		expectedFailed          bool
+		expectedSucceeded       int
+		expectedAllowedFailures int
+	}{
+		{
+			name:           "no jobs allowed to fail",
+			allowFailure:   []string{"other-job"},
+			expectedFailed: true,
+		},
+		{
+			name:                    "some jobs allowed to fail",
+			allowFailure:            []string{"test-job-4-*"},
+			expectedFailed:          true,
+			expectedSucceeded:       1,
+			expectedAllowedFailures: 1,
+		},
+		{
+			name:                    "all jobs allowed to fail",
+			allowFailure:            []string{"re:^test-job-"},
+			expectedSucceeded:       2,
+			expectedAllowedFailures: 3,
+		},
+	} {
+		t.Run(test.name, func(tt *testing.T) {
+			m := newMockCircleClientWithData("failed", "failed", "failed", "failed")
+			f, err := NewNameFilter(test.allowFailure, nil)
+			if err != nil {
+				tt.Fatalf("unexpected error: %v", err)
+			}
+
+			result, err := checkWorkflowsStatus(context.Background(), m, "456", checkWorkflowStatusOpts{
+				allowFailure:     filterJobByName(f, nil),
+				failedJobDetails: true,
+			})
+			if err != nil {
+				tt.Fatalf("unexpected error: %v", err)
+			}
+
+			if want, got := test.expectedFailed, result.Failed; want != got {
+				tt.Errorf("invalid value for Failed; want %v, got %v", want, got)
+			}
+			if want, got := test.expectedSucceeded, len(result.SucceededWorkflows); want != got {
+				tt.Errorf("invalid number of succeeded workflows; want %v, got %v", want, got)
+			}
+
+			allowedFailures := 0
+			for _, details := range result.AllWorkflows {
+				allowedFailures += len(details.AllowedFailedJobs)
+			}
+			if want, got := test.expectedAllowedFailures, allowedFailures; want != got {
+				tt.Errorf("invalid number of allowed failures; want %v, got %v", want, got)
+			}
+		})
+	}
+}